@@ -78,6 +78,15 @@ func Routes(states []*state.State) (err error) {
 		}
 	}
 
+	if curRoutes.Openstack != nil {
+		for _, route := range curRoutes.Openstack {
+			err = OpenstackDeleteRoute(route)
+			if err != nil {
+				return
+			}
+		}
+	}
+
 	if curRoutes.Aws != nil {
 		for _, route := range curRoutes.Aws {
 			err = AwsDeleteRoute(route)
@@ -96,6 +105,24 @@ func Routes(states []*state.State) (err error) {
 		}
 	}
 
+	if curRoutes.Alibaba != nil {
+		for _, route := range curRoutes.Alibaba {
+			err = AlibabaDeleteRoute(route)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	if curRoutes.Ibm != nil {
+		for _, route := range curRoutes.Ibm {
+			err = IbmDeleteRoute(route)
+			if err != nil {
+				return
+			}
+		}
+	}
+
 	if curRoutes.Unifi != nil {
 		for _, route := range curRoutes.Unifi {
 			err = UnifiDeleteRoute(route)
@@ -114,6 +141,33 @@ func Routes(states []*state.State) (err error) {
 		}
 	}
 
+	if curRoutes.Vyos != nil {
+		for _, route := range curRoutes.Vyos {
+			err = VyosDeleteRoute(route)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	if curRoutes.Frr != nil {
+		for _, route := range curRoutes.Frr {
+			err = FrrDeleteRoute(route)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	if curRoutes.Ssh != nil {
+		for _, route := range curRoutes.Ssh {
+			err = SshDeleteRoute(route)
+			if err != nil {
+				return
+			}
+		}
+	}
+
 	if curRoutes.Pritunl != nil {
 		for _, route := range curRoutes.Pritunl {
 			err = PritunlDeleteRoute(route)
@@ -123,6 +177,13 @@ func Routes(states []*state.State) (err error) {
 		}
 	}
 
+	if config.Config.Provider == "ssh" {
+		err = SshAddRoutes(availableNetworks)
+		if err != nil {
+			return
+		}
+	}
+
 	for _, network := range availableNetworks {
 		switch config.Config.Provider {
 		case "aws":
@@ -138,6 +199,20 @@ func Routes(states []*state.State) (err error) {
 				return
 			}
 
+			break
+		case "alibaba":
+			err = AlibabaAddRoute(network)
+			if err != nil {
+				return
+			}
+
+			break
+		case "ibm":
+			err = IbmAddRoute(network)
+			if err != nil {
+				return
+			}
+
 			break
 		case "google":
 			err = GoogleAddRoute(network)
@@ -159,6 +234,13 @@ func Routes(states []*state.State) (err error) {
 				return
 			}
 
+			break
+		case "openstack":
+			err = OpenstackAddRoute(network)
+			if err != nil {
+				return
+			}
+
 			break
 		case "unifi":
 			err = UnifiAddRoute(network)
@@ -173,6 +255,20 @@ func Routes(states []*state.State) (err error) {
 				return
 			}
 
+			break
+		case "vyos":
+			err = VyosAddRoute(network)
+			if err != nil {
+				return
+			}
+
+			break
+		case "frr":
+			err = FrrAddRoute(network)
+			if err != nil {
+				return
+			}
+
 			break
 		case "pritunl":
 			err = PritunlAddRoute(network)
@@ -244,6 +340,15 @@ func Ports(states []*state.State) (err error) {
 			}
 		}
 
+		break
+	case "vyos":
+		if !config.Config.Vyos.DisablePort {
+			err = VyosAddPorts()
+			if err != nil {
+				return
+			}
+		}
+
 		break
 	}
 
@@ -0,0 +1,519 @@
+package advertise
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/constants"
+	"github.com/pritunl/pritunl-link/errortypes"
+	"github.com/pritunl/pritunl-link/routes"
+	"github.com/pritunl/pritunl-link/utils"
+)
+
+const alibabaMetadataUrl = "http://100.100.100.200/latest/meta-data/"
+
+var (
+	alibabaMetadataClient = &http.Client{
+		Timeout: 2 * time.Second,
+	}
+	alibabaClient = &http.Client{
+		Timeout: 20 * time.Second,
+	}
+)
+
+type alibabaMetaData struct {
+	Region          string
+	VpcId           string
+	InstanceId      string
+	AccessKeyId     string
+	AccessKeySecret string
+	SecurityToken   string
+}
+
+type alibabaRamCredentials struct {
+	AccessKeyId     string `json:"AccessKeyId"`
+	AccessKeySecret string `json:"AccessKeySecret"`
+	SecurityToken   string `json:"SecurityToken"`
+	Code            string `json:"Code"`
+}
+
+type alibabaError struct {
+	Code    string `json:"Code"`
+	Message string `json:"Message"`
+}
+
+type alibabaRouteTable struct {
+	RouteTableId string `json:"RouteTableId"`
+}
+
+type alibabaRouteTablesResp struct {
+	TotalCount      int `json:"TotalCount"`
+	RouterTableList struct {
+		RouterTableListType []alibabaRouteTable `json:"RouterTableListType"`
+	} `json:"RouterTableList"`
+}
+
+type alibabaNextHop struct {
+	NextHopId   string `json:"NextHopId"`
+	NextHopType string `json:"NextHopType"`
+}
+
+type alibabaRouteEntry struct {
+	DestinationCidrBlock string `json:"DestinationCidrBlock"`
+	Type                 string `json:"Type"`
+	Status               string `json:"Status"`
+	NextHops             struct {
+		NextHop []alibabaNextHop `json:"NextHop"`
+	} `json:"NextHops"`
+}
+
+type alibabaRouteEntriesResp struct {
+	RouteEntrys struct {
+		RouteEntry []alibabaRouteEntry `json:"RouteEntry"`
+	} `json:"RouteEntrys"`
+}
+
+func alibabaGetMeta(path string) (val string, err error) {
+	resp, err := alibabaMetadataClient.Get(alibabaMetadataUrl + path)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "advertise: Failed to get Alibaba metadata"),
+		}
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "advertise: Failed to read Alibaba metadata"),
+		}
+		return
+	}
+
+	if resp.StatusCode != 200 {
+		err = &errortypes.RequestError{
+			errors.Newf("advertise: Alibaba metadata '%s' bad status %d",
+				path, resp.StatusCode),
+		}
+		return
+	}
+
+	val = strings.TrimSpace(string(body))
+
+	return
+}
+
+func alibabaGetMetaData() (data *alibabaMetaData, err error) {
+	data = &alibabaMetaData{
+		Region:          config.Config.Alibaba.Region,
+		VpcId:           config.Config.Alibaba.VpcId,
+		InstanceId:      config.Config.Alibaba.InstanceId,
+		AccessKeyId:     config.Config.Alibaba.AccessKeyId,
+		AccessKeySecret: config.Config.Alibaba.AccessKeySecret,
+	}
+
+	if data.Region == "" {
+		data.Region, err = alibabaGetMeta("region-id")
+		if err != nil {
+			return
+		}
+	}
+
+	if data.VpcId == "" {
+		data.VpcId, err = alibabaGetMeta("vpc-id")
+		if err != nil {
+			return
+		}
+	}
+
+	if data.InstanceId == "" {
+		data.InstanceId, err = alibabaGetMeta("instance-id")
+		if err != nil {
+			return
+		}
+	}
+
+	if data.AccessKeyId == "" || data.AccessKeySecret == "" {
+		role, e := alibabaGetMeta("ram/security-credentials/")
+		if e != nil {
+			err = e
+			return
+		}
+
+		credsData, e := alibabaGetMeta(
+			"ram/security-credentials/" + role)
+		if e != nil {
+			err = e
+			return
+		}
+
+		creds := &alibabaRamCredentials{}
+		err = json.Unmarshal([]byte(credsData), creds)
+		if err != nil {
+			err = &errortypes.ParseError{
+				errors.Wrap(err,
+					"advertise: Failed to parse Alibaba RAM credentials"),
+			}
+			return
+		}
+
+		if creds.AccessKeyId == "" {
+			err = &errortypes.ParseError{
+				errors.Newf("advertise: Alibaba RAM credentials "+
+					"unavailable '%s'", creds.Code),
+			}
+			return
+		}
+
+		data.AccessKeyId = creds.AccessKeyId
+		data.AccessKeySecret = creds.AccessKeySecret
+		data.SecurityToken = creds.SecurityToken
+	}
+
+	return
+}
+
+func alibabaEscape(val string) string {
+	val = url.QueryEscape(val)
+	val = strings.Replace(val, "+", "%20", -1)
+	val = strings.Replace(val, "*", "%2A", -1)
+	val = strings.Replace(val, "%7E", "~", -1)
+	return val
+}
+
+func alibabaRequest(data *alibabaMetaData, action string,
+	params map[string]string, output interface{}) (err error) {
+
+	nonce, err := utils.RandStr(32)
+	if err != nil {
+		return
+	}
+
+	query := map[string]string{
+		"Action":           action,
+		"Format":           "JSON",
+		"Version":          "2016-04-28",
+		"RegionId":         data.Region,
+		"AccessKeyId":      data.AccessKeyId,
+		"SignatureMethod":  "HMAC-SHA1",
+		"SignatureVersion": "1.0",
+		"SignatureNonce":   nonce,
+		"Timestamp": time.Now().UTC().Format(
+			"2006-01-02T15:04:05Z"),
+	}
+	if data.SecurityToken != "" {
+		query["SecurityToken"] = data.SecurityToken
+	}
+	for key, val := range params {
+		query[key] = val
+	}
+
+	keys := []string{}
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	canonical := []string{}
+	for _, key := range keys {
+		canonical = append(canonical,
+			alibabaEscape(key)+"="+alibabaEscape(query[key]))
+	}
+	canonicalStr := strings.Join(canonical, "&")
+
+	strToSign := "GET&" + alibabaEscape("/") + "&" +
+		alibabaEscape(canonicalStr)
+
+	hashFunc := hmac.New(sha1.New, []byte(data.AccessKeySecret+"&"))
+	hashFunc.Write([]byte(strToSign))
+	sig := base64.StdEncoding.EncodeToString(hashFunc.Sum(nil))
+
+	req, err := http.NewRequest(
+		"GET",
+		fmt.Sprintf("https://vpc.%s.aliyuncs.com/?%s&Signature=%s",
+			data.Region, canonicalStr, alibabaEscape(sig)),
+		nil,
+	)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "advertise: Alibaba request init error"),
+		}
+		return
+	}
+
+	resp, err := alibabaClient.Do(req)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrapf(err, "advertise: Alibaba %s request failed",
+				action),
+		}
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "advertise: Failed to read Alibaba response"),
+		}
+		return
+	}
+
+	if resp.StatusCode != 200 {
+		errData := &alibabaError{}
+		_ = json.Unmarshal(body, errData)
+
+		err = &errortypes.RequestError{
+			errors.Newf("advertise: Alibaba %s bad status %d %s '%s'",
+				action, resp.StatusCode, errData.Code, errData.Message),
+		}
+		return
+	}
+
+	if output != nil {
+		err = json.Unmarshal(body, output)
+		if err != nil {
+			err = &errortypes.ParseError{
+				errors.Wrap(err,
+					"advertise: Failed to parse Alibaba response"),
+			}
+			return
+		}
+	}
+
+	return
+}
+
+func alibabaGetRouteTables(data *alibabaMetaData) (
+	tableIds []string, err error) {
+
+	tableIds = []string{}
+
+	for page := 1; ; page++ {
+		resp := &alibabaRouteTablesResp{}
+
+		err = alibabaRequest(data, "DescribeRouteTableList",
+			map[string]string{
+				"VpcId":      data.VpcId,
+				"PageSize":   "50",
+				"PageNumber": fmt.Sprintf("%d", page),
+			}, resp)
+		if err != nil {
+			return
+		}
+
+		tables := resp.RouterTableList.RouterTableListType
+		for _, table := range tables {
+			tableIds = append(tableIds, table.RouteTableId)
+		}
+
+		if len(tables) == 0 || len(tableIds) >= resp.TotalCount {
+			break
+		}
+	}
+
+	return
+}
+
+func alibabaGetRouteEntries(data *alibabaMetaData, tableId,
+	network string) (entries []alibabaRouteEntry, err error) {
+
+	resp := &alibabaRouteEntriesResp{}
+
+	err = alibabaRequest(data, "DescribeRouteEntryList",
+		map[string]string{
+			"RouteTableId":         tableId,
+			"DestinationCidrBlock": network,
+		}, resp)
+	if err != nil {
+		return
+	}
+
+	entries = resp.RouteEntrys.RouteEntry
+
+	return
+}
+
+func alibabaCreateRouteEntry(data *alibabaMetaData, tableId,
+	network string) (err error) {
+
+	for i := 0; i < 10; i++ {
+		err = alibabaRequest(data, "CreateRouteEntry",
+			map[string]string{
+				"RouteTableId":         tableId,
+				"DestinationCidrBlock": network,
+				"NextHopType":          "Instance",
+				"NextHopId":            data.InstanceId,
+				"Description":          "pritunl-link",
+			}, nil)
+		if err == nil {
+			return
+		}
+
+		time.Sleep(2 * time.Second)
+	}
+
+	return
+}
+
+func AlibabaAddRoute(network string) (err error) {
+	time.Sleep(150 * time.Millisecond)
+
+	if constants.Interrupt {
+		err = &errortypes.UnknownError{
+			errors.Wrap(err, "advertise: Interrupt"),
+		}
+		return
+	}
+
+	data, err := alibabaGetMetaData()
+	if err != nil {
+		return
+	}
+
+	tableIds, err := alibabaGetRouteTables(data)
+	if err != nil {
+		return
+	}
+
+	for _, tableId := range tableIds {
+		entries, e := alibabaGetRouteEntries(data, tableId, network)
+		if e != nil {
+			err = e
+			return
+		}
+
+		exists := false
+
+		for _, entry := range entries {
+			if entry.DestinationCidrBlock != network ||
+				entry.Type != "Custom" {
+
+				continue
+			}
+
+			for _, nextHop := range entry.NextHops.NextHop {
+				if nextHop.NextHopId == data.InstanceId {
+					exists = true
+				} else {
+					err = alibabaRequest(data, "DeleteRouteEntry",
+						map[string]string{
+							"RouteTableId":         tableId,
+							"DestinationCidrBlock": network,
+							"NextHopId":            nextHop.NextHopId,
+						}, nil)
+					if err != nil {
+						return
+					}
+				}
+			}
+		}
+
+		if exists {
+			continue
+		}
+
+		err = alibabaCreateRouteEntry(data, tableId, network)
+		if err != nil {
+			return
+		}
+	}
+
+	route := &routes.AlibabaRoute{
+		DestNetwork: network,
+		Region:      data.Region,
+		VpcId:       data.VpcId,
+		InstanceId:  data.InstanceId,
+	}
+
+	err = route.Add()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func AlibabaDeleteRoute(route *routes.AlibabaRoute) (err error) {
+	if config.Config.DeleteRoutes {
+		time.Sleep(150 * time.Millisecond)
+
+		if constants.Interrupt {
+			err = &errortypes.UnknownError{
+				errors.Wrap(err, "advertise: Interrupt"),
+			}
+			return
+		}
+
+		data, e := alibabaGetMetaData()
+		if e != nil {
+			err = e
+			return
+		}
+		data.Region = route.Region
+		data.VpcId = route.VpcId
+
+		tableIds, e := alibabaGetRouteTables(data)
+		if e != nil {
+			err = e
+			return
+		}
+
+		for _, tableId := range tableIds {
+			entries, e := alibabaGetRouteEntries(
+				data, tableId, route.DestNetwork)
+			if e != nil {
+				err = e
+				return
+			}
+
+			exists := false
+			for _, entry := range entries {
+				if entry.DestinationCidrBlock != route.DestNetwork ||
+					entry.Type != "Custom" {
+
+					continue
+				}
+
+				for _, nextHop := range entry.NextHops.NextHop {
+					if nextHop.NextHopId == route.InstanceId {
+						exists = true
+					}
+				}
+			}
+
+			if !exists {
+				continue
+			}
+
+			err = alibabaRequest(data, "DeleteRouteEntry",
+				map[string]string{
+					"RouteTableId":         tableId,
+					"DestinationCidrBlock": route.DestNetwork,
+					"NextHopId":            route.InstanceId,
+				}, nil)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	err = route.Remove()
+	if err != nil {
+		return
+	}
+
+	return
+}
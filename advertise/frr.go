@@ -0,0 +1,178 @@
+package advertise
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/constants"
+	"github.com/pritunl/pritunl-link/errortypes"
+	"github.com/pritunl/pritunl-link/routes"
+	"github.com/pritunl/pritunl-link/state"
+	"github.com/pritunl/pritunl-link/utils"
+)
+
+const frrDefaultTag = 9790
+
+func frrRouteCmd(route *routes.FrrRoute) (cmd string) {
+	ip, _, _ := net.ParseCIDR(route.Network)
+	if ip != nil && ip.To4() == nil {
+		cmd = "ipv6 route"
+	} else {
+		cmd = "ip route"
+	}
+
+	nexthop := route.Nexthop
+	if nexthop == "" {
+		nexthop = "blackhole"
+	}
+
+	cmd += fmt.Sprintf(" %s %s tag %d", route.Network, nexthop, route.Tag)
+
+	if route.Distance != 0 {
+		cmd += fmt.Sprintf(" %d", route.Distance)
+	}
+
+	if route.Vrf != "" {
+		cmd += " vrf " + route.Vrf
+	}
+
+	return
+}
+
+func frrGetNexthop(network string) (nexthop string) {
+	ip, _, err := net.ParseCIDR(network)
+	if err != nil {
+		return
+	}
+
+	if ip.To4() == nil {
+		nexthop = config.Config.Frr.NextHop6
+		if nexthop == "" {
+			nexthop = state.GetAddress6()
+		}
+
+		nexthopIp := net.ParseIP(nexthop)
+		if nexthopIp != nil && nexthopIp.To4() != nil {
+			nexthop = ""
+		}
+	} else {
+		nexthop = config.Config.Frr.NextHop
+		if nexthop == "" {
+			nexthop = state.GetLocalAddress()
+		}
+
+		nexthopIp := net.ParseIP(nexthop)
+		if nexthopIp != nil && nexthopIp.To4() == nil {
+			nexthop = ""
+		}
+	}
+
+	return
+}
+
+func frrConfigure(cmds ...string) (err error) {
+	args := []string{"-c", "configure terminal"}
+	for _, cmd := range cmds {
+		args = append(args, "-c", cmd)
+	}
+	args = append(args, "-c", "end")
+
+	output, err := utils.ExecCombinedOutput("", "vtysh", args...)
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "%") {
+			err = &errortypes.ExecError{
+				errors.Newf("advertise: FRR configure error '%s'", line),
+			}
+			return
+		}
+	}
+
+	return
+}
+
+func FrrAddRoute(network string) (err error) {
+	if constants.Interrupt {
+		err = &errortypes.UnknownError{
+			errors.Wrap(err, "advertise: Interrupt"),
+		}
+		return
+	}
+
+	tag := config.Config.Frr.Tag
+	if tag == 0 {
+		tag = frrDefaultTag
+	}
+
+	nexthop := frrGetNexthop(network)
+	if nexthop == "" {
+		return
+	}
+
+	route := &routes.FrrRoute{
+		Network:  network,
+		Nexthop:  nexthop,
+		Vrf:      config.Config.Frr.Vrf,
+		Tag:      tag,
+		Distance: config.Config.Frr.Distance,
+	}
+
+	curRoutes, err := routes.GetCurrent()
+	if err != nil {
+		return
+	}
+
+	cmds := []string{}
+
+	if curRoutes.Frr != nil {
+		if curRoute, ok := curRoutes.Frr[network]; ok &&
+			frrRouteCmd(curRoute) != frrRouteCmd(route) {
+
+			cmds = append(cmds, "no "+frrRouteCmd(curRoute))
+		}
+	}
+
+	cmds = append(cmds, frrRouteCmd(route))
+
+	err = frrConfigure(cmds...)
+	if err != nil {
+		return
+	}
+
+	err = route.Add()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func FrrDeleteRoute(route *routes.FrrRoute) (err error) {
+	if config.Config.DeleteRoutes {
+		if constants.Interrupt {
+			err = &errortypes.UnknownError{
+				errors.Wrap(err, "advertise: Interrupt"),
+			}
+			return
+		}
+
+		err = frrConfigure("no " + frrRouteCmd(route))
+		if err != nil {
+			return
+		}
+	}
+
+	err = route.Remove()
+	if err != nil {
+		return
+	}
+
+	return
+}
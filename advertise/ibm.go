@@ -0,0 +1,594 @@
+package advertise
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/constants"
+	"github.com/pritunl/pritunl-link/errortypes"
+	"github.com/pritunl/pritunl-link/routes"
+)
+
+const (
+	ibmMetadataUrl = "http://api.metadata.cloud.ibm.com"
+	ibmIamUrl      = "https://iam.cloud.ibm.com/identity/token"
+	ibmApiVersion  = "2024-04-30"
+	ibmMetaVersion = "2022-03-01"
+)
+
+var (
+	ibmMetadataClient = &http.Client{
+		Timeout: 2 * time.Second,
+	}
+	ibmClient = &http.Client{
+		Timeout: 20 * time.Second,
+	}
+)
+
+type ibmMetaData struct {
+	Region      string
+	Zone        string
+	VpcId       string
+	InstanceId  string
+	InterfaceId string
+	Address     string
+	Token       string
+}
+
+type ibmReference struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type ibmPrimaryIp struct {
+	Address string `json:"address"`
+}
+
+type ibmInterface struct {
+	Id              string       `json:"id"`
+	AllowIpSpoofing bool         `json:"allow_ip_spoofing"`
+	PrimaryIp       ibmPrimaryIp `json:"primary_ip"`
+}
+
+type ibmInstance struct {
+	Id                      string       `json:"id"`
+	Vpc                     ibmReference `json:"vpc"`
+	Zone                    ibmReference `json:"zone"`
+	PrimaryNetworkInterface ibmInterface `json:"primary_network_interface"`
+}
+
+type ibmToken struct {
+	AccessToken string `json:"access_token"`
+}
+
+type ibmRoutingTables struct {
+	RoutingTables []ibmReference `json:"routing_tables"`
+}
+
+type ibmNextHop struct {
+	Address string `json:"address"`
+}
+
+type ibmRoute struct {
+	Id          string       `json:"id,omitempty"`
+	Name        string       `json:"name,omitempty"`
+	Action      string       `json:"action,omitempty"`
+	Destination string       `json:"destination"`
+	Origin      string       `json:"origin,omitempty"`
+	Zone        ibmReference `json:"zone"`
+	NextHop     ibmNextHop   `json:"next_hop"`
+}
+
+type ibmRoutes struct {
+	Routes []*ibmRoute `json:"routes"`
+}
+
+func ibmRequest(method, reqUrl, token string, input,
+	output interface{}) (err error) {
+
+	var body io.Reader
+	if input != nil {
+		data, e := json.Marshal(input)
+		if e != nil {
+			err = &errortypes.ParseError{
+				errors.Wrap(e, "advertise: Failed to marshal IBM request"),
+			}
+			return
+		}
+		body = bytes.NewBuffer(data)
+	}
+
+	req, err := http.NewRequest(method, reqUrl, body)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "advertise: IBM request init error"),
+		}
+		return
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	if input != nil {
+		if method == "PATCH" {
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+		} else {
+			req.Header.Set("Content-Type", "application/json")
+		}
+	}
+
+	resp, err := ibmClient.Do(req)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrapf(err, "advertise: IBM %s request failed", method),
+		}
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		err = &errortypes.RequestError{
+			errors.Newf("advertise: IBM %s bad status %d '%s'",
+				method, resp.StatusCode,
+				strings.TrimSpace(string(respBody))),
+		}
+		return
+	}
+
+	if output != nil {
+		err = json.NewDecoder(resp.Body).Decode(output)
+		if err != nil {
+			err = &errortypes.ParseError{
+				errors.Wrap(err, "advertise: Failed to parse IBM response"),
+			}
+			return
+		}
+	}
+
+	return
+}
+
+func ibmGetToken() (token string, err error) {
+	form := url.Values{
+		"grant_type": []string{"urn:ibm:params:oauth:grant-type:apikey"},
+		"apikey":     []string{config.Config.Ibm.ApiKey},
+	}
+
+	req, err := http.NewRequest(
+		"POST",
+		ibmIamUrl,
+		strings.NewReader(form.Encode()),
+	)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "advertise: IBM token request init error"),
+		}
+		return
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := ibmClient.Do(req)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "advertise: IBM token request failed"),
+		}
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		err = &errortypes.RequestError{
+			errors.Newf("advertise: IBM token bad status %d",
+				resp.StatusCode),
+		}
+		return
+	}
+
+	data := &ibmToken{}
+	err = json.NewDecoder(resp.Body).Decode(data)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "advertise: Failed to parse IBM token"),
+		}
+		return
+	}
+
+	token = data.AccessToken
+
+	return
+}
+
+func ibmGetInstance() (instance *ibmInstance, err error) {
+	req, err := http.NewRequest(
+		"PUT",
+		fmt.Sprintf("%s/instance_identity/v1/token?version=%s",
+			ibmMetadataUrl, ibmMetaVersion),
+		strings.NewReader(`{"expires_in": 300}`),
+	)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "advertise: IBM metadata request init error"),
+		}
+		return
+	}
+	req.Header.Set("Metadata-Flavor", "ibm")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := ibmMetadataClient.Do(req)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "advertise: Failed to get IBM metadata token"),
+		}
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		err = &errortypes.RequestError{
+			errors.Newf("advertise: IBM metadata token bad status %d",
+				resp.StatusCode),
+		}
+		return
+	}
+
+	token := &ibmToken{}
+	err = json.NewDecoder(resp.Body).Decode(token)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "advertise: Failed to parse IBM metadata token"),
+		}
+		return
+	}
+
+	req, err = http.NewRequest(
+		"GET",
+		fmt.Sprintf("%s/metadata/v1/instance?version=%s",
+			ibmMetadataUrl, ibmMetaVersion),
+		nil,
+	)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "advertise: IBM metadata request init error"),
+		}
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+
+	instResp, err := ibmMetadataClient.Do(req)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "advertise: Failed to get IBM metadata"),
+		}
+		return
+	}
+	defer instResp.Body.Close()
+
+	if instResp.StatusCode != 200 {
+		err = &errortypes.RequestError{
+			errors.Newf("advertise: IBM metadata bad status %d",
+				instResp.StatusCode),
+		}
+		return
+	}
+
+	instance = &ibmInstance{}
+	err = json.NewDecoder(instResp.Body).Decode(instance)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "advertise: Failed to parse IBM metadata"),
+		}
+		return
+	}
+
+	return
+}
+
+func ibmGetMetaData() (data *ibmMetaData, err error) {
+	token, err := ibmGetToken()
+	if err != nil {
+		return
+	}
+
+	instance, err := ibmGetInstance()
+	if err != nil {
+		return
+	}
+
+	region := config.Config.Ibm.Region
+	if region == "" {
+		zoneName := instance.Zone.Name
+		index := strings.LastIndex(zoneName, "-")
+		if index > 0 {
+			region = zoneName[:index]
+		}
+	}
+
+	if region == "" {
+		err = &errortypes.ParseError{
+			errors.New("advertise: Failed to get IBM region"),
+		}
+		return
+	}
+
+	data = &ibmMetaData{
+		Region:      region,
+		Zone:        instance.Zone.Name,
+		VpcId:       instance.Vpc.Id,
+		InstanceId:  instance.Id,
+		InterfaceId: instance.PrimaryNetworkInterface.Id,
+		Address:     instance.PrimaryNetworkInterface.PrimaryIp.Address,
+		Token:       token,
+	}
+
+	return
+}
+
+func ibmApiUrl(region, path string, args ...interface{}) string {
+	return fmt.Sprintf("https://%s.iaas.cloud.ibm.com/v1%s?version=%s"+
+		"&generation=2", region, fmt.Sprintf(path, args...), ibmApiVersion)
+}
+
+func ibmGetRoutingTables(data *ibmMetaData) (
+	tableIds []string, err error) {
+
+	tables := &ibmRoutingTables{}
+
+	err = ibmRequest(
+		"GET",
+		ibmApiUrl(data.Region, "/vpcs/%s/routing_tables", data.VpcId),
+		data.Token,
+		nil,
+		tables,
+	)
+	if err != nil {
+		return
+	}
+
+	tableIds = []string{}
+	for _, table := range tables.RoutingTables {
+		tableIds = append(tableIds, table.Id)
+	}
+
+	return
+}
+
+func ibmGetRoutes(data *ibmMetaData, tableId string) (
+	rtes []*ibmRoute, err error) {
+
+	tableRoutes := &ibmRoutes{}
+
+	err = ibmRequest(
+		"GET",
+		ibmApiUrl(data.Region, "/vpcs/%s/routing_tables/%s/routes",
+			data.VpcId, tableId),
+		data.Token,
+		nil,
+		tableRoutes,
+	)
+	if err != nil {
+		return
+	}
+
+	rtes = tableRoutes.Routes
+
+	return
+}
+
+func ibmDeleteRoute(data *ibmMetaData, tableId, routeId string) (
+	err error) {
+
+	err = ibmRequest(
+		"DELETE",
+		ibmApiUrl(data.Region, "/vpcs/%s/routing_tables/%s/routes/%s",
+			data.VpcId, tableId, routeId),
+		data.Token,
+		nil,
+		nil,
+	)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func ibmSetIpSpoofing(data *ibmMetaData) (err error) {
+	iface := &ibmInterface{}
+
+	err = ibmRequest(
+		"GET",
+		ibmApiUrl(data.Region, "/instances/%s/network_interfaces/%s",
+			data.InstanceId, data.InterfaceId),
+		data.Token,
+		nil,
+		iface,
+	)
+	if err != nil {
+		return
+	}
+
+	if iface.AllowIpSpoofing {
+		return
+	}
+
+	err = ibmRequest(
+		"PATCH",
+		ibmApiUrl(data.Region, "/instances/%s/network_interfaces/%s",
+			data.InstanceId, data.InterfaceId),
+		data.Token,
+		map[string]interface{}{
+			"allow_ip_spoofing": true,
+		},
+		nil,
+	)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func IbmAddRoute(network string) (err error) {
+	time.Sleep(150 * time.Millisecond)
+
+	if constants.Interrupt {
+		err = &errortypes.UnknownError{
+			errors.Wrap(err, "advertise: Interrupt"),
+		}
+		return
+	}
+
+	data, err := ibmGetMetaData()
+	if err != nil {
+		return
+	}
+
+	err = ibmSetIpSpoofing(data)
+	if err != nil {
+		return
+	}
+
+	tableIds, err := ibmGetRoutingTables(data)
+	if err != nil {
+		return
+	}
+
+	for _, tableId := range tableIds {
+		rtes, e := ibmGetRoutes(data, tableId)
+		if e != nil {
+			err = e
+			return
+		}
+
+		exists := false
+
+		for _, rte := range rtes {
+			if rte.Destination != network || rte.Zone.Name != data.Zone ||
+				rte.Origin == "service" {
+
+				continue
+			}
+
+			if rte.NextHop.Address == data.Address &&
+				rte.Action == "deliver" {
+
+				exists = true
+				continue
+			}
+
+			err = ibmDeleteRoute(data, tableId, rte.Id)
+			if err != nil {
+				return
+			}
+		}
+
+		if exists {
+			continue
+		}
+
+		err = ibmRequest(
+			"POST",
+			ibmApiUrl(data.Region, "/vpcs/%s/routing_tables/%s/routes",
+				data.VpcId, tableId),
+			data.Token,
+			&ibmRoute{
+				Action:      "deliver",
+				Destination: network,
+				Zone: ibmReference{
+					Name: data.Zone,
+				},
+				NextHop: ibmNextHop{
+					Address: data.Address,
+				},
+			},
+			nil,
+		)
+		if err != nil {
+			return
+		}
+	}
+
+	route := &routes.IbmRoute{
+		DestNetwork: network,
+		Region:      data.Region,
+		Zone:        data.Zone,
+		VpcId:       data.VpcId,
+		NextHop:     data.Address,
+	}
+
+	err = route.Add()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func IbmDeleteRoute(route *routes.IbmRoute) (err error) {
+	if config.Config.DeleteRoutes {
+		time.Sleep(150 * time.Millisecond)
+
+		if constants.Interrupt {
+			err = &errortypes.UnknownError{
+				errors.Wrap(err, "advertise: Interrupt"),
+			}
+			return
+		}
+
+		token, e := ibmGetToken()
+		if e != nil {
+			err = e
+			return
+		}
+
+		data := &ibmMetaData{
+			Region: route.Region,
+			Zone:   route.Zone,
+			VpcId:  route.VpcId,
+			Token:  token,
+		}
+
+		tableIds, e := ibmGetRoutingTables(data)
+		if e != nil {
+			err = e
+			return
+		}
+
+		for _, tableId := range tableIds {
+			rtes, e := ibmGetRoutes(data, tableId)
+			if e != nil {
+				err = e
+				return
+			}
+
+			for _, rte := range rtes {
+				if rte.Destination == route.DestNetwork &&
+					rte.Zone.Name == route.Zone &&
+					rte.NextHop.Address == route.NextHop {
+
+					err = ibmDeleteRoute(data, tableId, rte.Id)
+					if err != nil {
+						return
+					}
+				}
+			}
+		}
+	}
+
+	err = route.Remove()
+	if err != nil {
+		return
+	}
+
+	return
+}
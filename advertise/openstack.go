@@ -0,0 +1,182 @@
+package advertise
+
+import (
+	"strings"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/constants"
+	"github.com/pritunl/pritunl-link/errortypes"
+	"github.com/pritunl/pritunl-link/openstack"
+	"github.com/pritunl/pritunl-link/routes"
+	"github.com/pritunl/pritunl-link/state"
+)
+
+func openstackGetPort(pv *openstack.Provider) (
+	port *openstack.Port, err error) {
+
+	portId := config.Config.Openstack.PortId
+	if portId != "" {
+		port, err = openstack.GetPort(pv, portId)
+		return
+	}
+
+	mdata, err := openstack.GetMetadata()
+	if err != nil {
+		return
+	}
+
+	port, err = openstack.GetInstancePort(
+		pv, mdata.InstanceId, state.GetLocalAddress())
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func openstackGetNexthop(port *openstack.Port, network string) string {
+	if strings.Contains(network, ":") {
+		for _, fixedIp := range port.FixedIps {
+			if strings.Contains(fixedIp.IpAddress, ":") {
+				return fixedIp.IpAddress
+			}
+		}
+		return ""
+	}
+
+	fixedIp := port.GetFixedIp(state.GetLocalAddress())
+	if fixedIp == nil {
+		fixedIp = port.GetFixedIp("")
+	}
+	if fixedIp == nil {
+		return ""
+	}
+
+	return fixedIp.IpAddress
+}
+
+func OpenstackAddRoute(network string) (err error) {
+	time.Sleep(150 * time.Millisecond)
+
+	if constants.Interrupt {
+		err = &errortypes.UnknownError{
+			errors.Wrap(err, "advertise: Interrupt"),
+		}
+		return
+	}
+
+	pv, err := openstack.NewProvider()
+	if err != nil {
+		return
+	}
+
+	port, err := openstackGetPort(pv)
+	if err != nil {
+		return
+	}
+
+	nexthop := openstackGetNexthop(port, network)
+	if nexthop == "" {
+		err = &errortypes.ParseError{
+			errors.Newf("openstack: Port missing address for '%s'",
+				network),
+		}
+		return
+	}
+
+	err = port.AddAddressPair(pv, network)
+	if err != nil {
+		return
+	}
+
+	routerIds := []string{}
+	if config.Config.Openstack.RouterId != "" {
+		routerIds = append(routerIds, config.Config.Openstack.RouterId)
+	} else {
+		routerIds, err = openstack.GetPortRouterIds(pv, port)
+		if err != nil {
+			return
+		}
+	}
+
+	for _, routerId := range routerIds {
+		router, e := openstack.GetRouter(pv, routerId)
+		if e != nil {
+			err = e
+			return
+		}
+
+		err = router.RouteUpsert(pv, network, nexthop)
+		if err != nil {
+			return
+		}
+	}
+
+	route := &routes.OpenstackRoute{
+		DestNetwork: network,
+		PortId:      port.Id,
+		RouterIds:   routerIds,
+		Nexthop:     nexthop,
+	}
+
+	err = route.Add()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func OpenstackDeleteRoute(route *routes.OpenstackRoute) (err error) {
+	if config.Config.DeleteRoutes {
+		time.Sleep(150 * time.Millisecond)
+
+		if constants.Interrupt {
+			err = &errortypes.UnknownError{
+				errors.Wrap(err, "advertise: Interrupt"),
+			}
+			return
+		}
+
+		pv, e := openstack.NewProvider()
+		if e != nil {
+			err = e
+			return
+		}
+
+		for _, routerId := range route.RouterIds {
+			router, e := openstack.GetRouter(pv, routerId)
+			if e != nil {
+				err = e
+				return
+			}
+
+			err = router.RouteRemove(pv, route.DestNetwork, route.Nexthop)
+			if err != nil {
+				return
+			}
+		}
+
+		if route.PortId != "" {
+			port, e := openstack.GetPort(pv, route.PortId)
+			if e != nil {
+				err = e
+				return
+			}
+
+			err = port.RemoveAddressPair(pv, route.DestNetwork)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	err = route.Remove()
+	if err != nil {
+		return
+	}
+
+	return
+}
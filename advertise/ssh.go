@@ -0,0 +1,357 @@
+package advertise
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/constants"
+	"github.com/pritunl/pritunl-link/errortypes"
+	"github.com/pritunl/pritunl-link/routes"
+	"github.com/pritunl/pritunl-link/state"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	sshDefaultPort          = "22"
+	sshDefaultUsername      = "root"
+	sshDefaultAddCommand    = "ip route replace {network} via {nexthop}"
+	sshDefaultDeleteCommand = "ip route del {network} via {nexthop}"
+)
+
+func sshGetClientConfig() (clientConf *ssh.ClientConfig, err error) {
+	keyData, err := ioutil.ReadFile(config.Config.Ssh.PrivateKey)
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "advertise: Failed to read SSH private key"),
+		}
+		return
+	}
+
+	signer, err := ssh.ParsePrivateKey(keyData)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "advertise: Failed to parse SSH private key"),
+		}
+		return
+	}
+
+	if config.Config.Ssh.KnownHosts == "" {
+		err = &errortypes.ReadError{
+			errors.New("advertise: SSH known hosts not configured"),
+		}
+		return
+	}
+
+	hostKeyCallback, err := knownhosts.New(config.Config.Ssh.KnownHosts)
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "advertise: Failed to read SSH known hosts"),
+		}
+		return
+	}
+
+	username := config.Config.Ssh.Username
+	if username == "" {
+		username = sshDefaultUsername
+	}
+
+	clientConf = &ssh.ClientConfig{
+		User: username,
+		Auth: []ssh.AuthMethod{
+			ssh.PublicKeys(signer),
+		},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         10 * time.Second,
+	}
+
+	return
+}
+
+func sshGetNexthop(network string) string {
+	ip, _, err := net.ParseCIDR(network)
+	if err != nil {
+		return ""
+	}
+
+	if ip.To4() == nil {
+		return state.GetAddress6()
+	}
+	return state.GetLocalAddress()
+}
+
+func sshFormatCommand(cmd, network, nexthop string) (
+	formatted string, err error) {
+
+	_, ipNet, err := net.ParseCIDR(network)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrapf(err, "advertise: Invalid SSH route network '%s'",
+				network),
+		}
+		return
+	}
+
+	nexthopIp := net.ParseIP(nexthop)
+	if nexthopIp == nil {
+		err = &errortypes.ParseError{
+			errors.Newf("advertise: Invalid SSH route nexthop '%s'",
+				nexthop),
+		}
+		return
+	}
+
+	formatted = strings.Replace(cmd, "{network}", ipNet.String(), -1)
+	formatted = strings.Replace(formatted, "{nexthop}",
+		nexthopIp.String(), -1)
+
+	return
+}
+
+func sshExec(clientConf *ssh.ClientConfig, host string,
+	cmds []string) (err error) {
+
+	addr := host
+	if _, _, e := net.SplitHostPort(host); e != nil {
+		addr = net.JoinHostPort(host, sshDefaultPort)
+	}
+
+	client, err := ssh.Dial("tcp", addr, clientConf)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrapf(err, "advertise: Failed to connect to SSH host '%s'",
+				host),
+		}
+		return
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrapf(err, "advertise: Failed to open SSH session '%s'",
+				host),
+		}
+		return
+	}
+	defer session.Close()
+
+	output := &bytes.Buffer{}
+	session.Stdout = output
+	session.Stderr = output
+
+	err = session.Run(strings.Join(cmds, "\n"))
+	if err != nil {
+		err = &errortypes.ExecError{
+			errors.Wrapf(err, "advertise: SSH command failed on '%s' '%s'",
+				host, strings.TrimSpace(output.String())),
+		}
+		return
+	}
+
+	return
+}
+
+func SshAddRoutes(networks []string) (err error) {
+	if constants.Interrupt {
+		err = &errortypes.UnknownError{
+			errors.Wrap(err, "advertise: Interrupt"),
+		}
+		return
+	}
+
+	hosts := config.Config.Ssh.Hosts
+	if len(networks) == 0 {
+		return
+	}
+
+	curRoutes, err := routes.GetCurrent()
+	if err != nil {
+		return
+	}
+
+	if len(hosts) == 0 && len(curRoutes.Ssh) == 0 {
+		return
+	}
+
+	clientConf, err := sshGetClientConfig()
+	if err != nil {
+		return
+	}
+
+	addCmd := config.Config.Ssh.AddCommand
+	if addCmd == "" {
+		addCmd = sshDefaultAddCommand
+	}
+
+	deleteCmd := config.Config.Ssh.DeleteCommand
+	if deleteCmd == "" {
+		deleteCmd = sshDefaultDeleteCommand
+	}
+
+	hostsSet := set.NewSet()
+	for _, host := range hosts {
+		hostsSet.Add(host)
+	}
+
+	cmds := []string{}
+	rtes := []*routes.SshRoute{}
+	for _, network := range networks {
+		nexthop := sshGetNexthop(network)
+		if nexthop == "" {
+			continue
+		}
+
+		cmd, e := sshFormatCommand(addCmd, network, nexthop)
+		if e != nil {
+			logrus.WithFields(logrus.Fields{
+				"network": network,
+				"error":   e,
+			}).Error("advertise: Skipping invalid SSH route")
+			continue
+		}
+
+		route := &routes.SshRoute{
+			Network: network,
+			Nexthop: nexthop,
+		}
+
+		if curRoutes.Ssh != nil {
+			if curRoute, ok := curRoutes.Ssh[network]; ok {
+				for _, host := range curRoute.Hosts {
+					if hostsSet.Contains(host) {
+						route.Hosts = append(route.Hosts, host)
+						continue
+					}
+
+					delCmd, e := sshFormatCommand(deleteCmd,
+						curRoute.Network, curRoute.Nexthop)
+					if e == nil {
+						e = sshExec(clientConf, host, []string{delCmd})
+					}
+					if e != nil {
+						logrus.WithFields(logrus.Fields{
+							"host":    host,
+							"network": network,
+							"error":   e,
+						}).Error("advertise: Failed to delete SSH host route")
+						route.Hosts = append(route.Hosts, host)
+					}
+				}
+			}
+		}
+
+		cmds = append(cmds, cmd)
+		rtes = append(rtes, route)
+	}
+
+	if len(cmds) == 0 {
+		return
+	}
+
+	addedHosts := []string{}
+	for _, host := range hosts {
+		e := sshExec(clientConf, host, cmds)
+		if e != nil {
+			logrus.WithFields(logrus.Fields{
+				"host":  host,
+				"error": e,
+			}).Error("advertise: Failed to add SSH host routes")
+			continue
+		}
+
+		addedHosts = append(addedHosts, host)
+	}
+
+	for _, route := range rtes {
+		routeHosts := set.NewSet()
+		for _, host := range route.Hosts {
+			routeHosts.Add(host)
+		}
+
+		for _, host := range addedHosts {
+			if !routeHosts.Contains(host) {
+				route.Hosts = append(route.Hosts, host)
+			}
+		}
+
+		if len(route.Hosts) == 0 {
+			continue
+		}
+
+		err = route.Add()
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func SshDeleteRoute(route *routes.SshRoute) (err error) {
+	if config.Config.DeleteRoutes && len(route.Hosts) > 0 {
+		if constants.Interrupt {
+			err = &errortypes.UnknownError{
+				errors.Wrap(err, "advertise: Interrupt"),
+			}
+			return
+		}
+
+		clientConf, e := sshGetClientConfig()
+		if e != nil {
+			err = e
+			return
+		}
+
+		deleteCmd := config.Config.Ssh.DeleteCommand
+		if deleteCmd == "" {
+			deleteCmd = sshDefaultDeleteCommand
+		}
+
+		cmd, e := sshFormatCommand(deleteCmd, route.Network, route.Nexthop)
+		if e != nil {
+			logrus.WithFields(logrus.Fields{
+				"network": route.Network,
+				"error":   e,
+			}).Error("advertise: Skipping invalid SSH route")
+		} else {
+			failedHosts := []string{}
+			for _, host := range route.Hosts {
+				e = sshExec(clientConf, host, []string{cmd})
+				if e != nil {
+					logrus.WithFields(logrus.Fields{
+						"host":    host,
+						"network": route.Network,
+						"error":   e,
+					}).Error("advertise: Failed to delete SSH host route")
+					failedHosts = append(failedHosts, host)
+					err = e
+				}
+			}
+
+			if len(failedHosts) > 0 {
+				route.Hosts = failedHosts
+
+				e = route.Add()
+				if e != nil {
+					err = e
+				}
+				return
+			}
+		}
+	}
+
+	err = route.Remove()
+	if err != nil {
+		return
+	}
+
+	return
+}
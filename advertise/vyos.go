@@ -0,0 +1,411 @@
+package advertise
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/errortypes"
+	"github.com/pritunl/pritunl-link/routes"
+	"github.com/pritunl/pritunl-link/state"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	vyosDescription      = "pritunl-link"
+	vyosDefaultInterface = "eth0"
+	vyosRuleStart        = 9790
+)
+
+var vyosClient = &http.Client{
+	Timeout: 20 * time.Second,
+	Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: true,
+		},
+	},
+}
+
+type vyosOp struct {
+	Op   string   `json:"op"`
+	Path []string `json:"path"`
+}
+
+type vyosResp struct {
+	Success bool            `json:"success"`
+	Data    json.RawMessage `json:"data"`
+	Error   string          `json:"error"`
+}
+
+type vyosRouteConf struct {
+	Description string                     `json:"description"`
+	NextHop     map[string]json.RawMessage `json:"next-hop"`
+}
+
+type vyosNatPort struct {
+	Port string `json:"port"`
+}
+
+type vyosNatAddress struct {
+	Address string `json:"address"`
+}
+
+type vyosNatInterface struct {
+	Name string `json:"name"`
+}
+
+type vyosNatRule struct {
+	Description      string           `json:"description"`
+	InboundInterface vyosNatInterface `json:"inbound-interface"`
+	Protocol         string           `json:"protocol"`
+	Destination      vyosNatPort      `json:"destination"`
+	Translation      vyosNatAddress   `json:"translation"`
+}
+
+type vyosPort struct {
+	Port     string
+	Protocol string
+}
+
+var vyosPorts = []vyosPort{
+	{"500", "udp"},
+	{"4500", "udp"},
+	{"9790", "tcp"},
+}
+
+func vyosRequest(endpoint string, ops interface{}) (
+	data json.RawMessage, err error) {
+
+	opsData, err := json.Marshal(ops)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "vyos: Json parse error"),
+		}
+		return
+	}
+
+	form := url.Values{
+		"data": []string{string(opsData)},
+		"key":  []string{config.Config.Vyos.Key},
+	}
+
+	req, err := http.NewRequest(
+		"POST",
+		fmt.Sprintf("https://%s/%s", config.Config.Vyos.Hostname, endpoint),
+		strings.NewReader(form.Encode()),
+	)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrapf(err, "vyos: VyOS %s request error", endpoint),
+		}
+		return
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := vyosClient.Do(req)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrapf(err, "vyos: VyOS %s request failed", endpoint),
+		}
+		return
+	}
+	defer resp.Body.Close()
+
+	respData := &vyosResp{}
+	err = json.NewDecoder(resp.Body).Decode(respData)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrapf(err, "vyos: Failed to parse %s response (%d)",
+				endpoint, resp.StatusCode),
+		}
+		return
+	}
+
+	if !respData.Success {
+		err = &errortypes.RequestError{
+			errors.Newf("vyos: VyOS %s error '%s'",
+				endpoint, respData.Error),
+		}
+		return
+	}
+
+	data = respData.Data
+
+	return
+}
+
+func vyosShowConfig(path []string, conf interface{}) (
+	exists bool, err error) {
+
+	data, err := vyosRequest("retrieve", &vyosOp{
+		Op:   "exists",
+		Path: path,
+	})
+	if err != nil {
+		return
+	}
+
+	_ = json.Unmarshal(data, &exists)
+	if !exists {
+		return
+	}
+
+	data, err = vyosRequest("retrieve", &vyosOp{
+		Op:   "showConfig",
+		Path: path,
+	})
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(data, conf)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "vyos: Failed to parse config data"),
+		}
+		return
+	}
+
+	return
+}
+
+func vyosConfigure(ops []*vyosOp) (err error) {
+	if len(ops) == 0 {
+		return
+	}
+
+	_, err = vyosRequest("configure", ops)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func vyosRoutePath(destination string, path ...string) []string {
+	return append([]string{
+		"protocols", "static", "route", destination}, path...)
+}
+
+func vyosAddRoute(destination string) (err error) {
+	nexthop := state.GetLocalAddress()
+
+	routeConf := &vyosRouteConf{}
+	exists, err := vyosShowConfig(vyosRoutePath(destination), routeConf)
+	if err != nil {
+		return
+	}
+
+	_, hasNexthop := routeConf.NextHop[nexthop]
+	if !exists || len(routeConf.NextHop) != 1 || !hasNexthop ||
+		routeConf.Description != vyosDescription {
+
+		ops := []*vyosOp{}
+
+		if exists {
+			ops = append(ops, &vyosOp{
+				Op:   "delete",
+				Path: vyosRoutePath(destination),
+			})
+		}
+
+		ops = append(ops,
+			&vyosOp{
+				Op:   "set",
+				Path: vyosRoutePath(destination, "next-hop", nexthop),
+			},
+			&vyosOp{
+				Op: "set",
+				Path: vyosRoutePath(destination,
+					"description", vyosDescription),
+			},
+		)
+
+		err = vyosConfigure(ops)
+		if err != nil {
+			return
+		}
+	}
+
+	route := &routes.VyosRoute{
+		Network: destination,
+		Nexthop: nexthop,
+	}
+
+	err = route.Add()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func vyosDeleteRoute(route *routes.VyosRoute) (err error) {
+	if config.Config.DeleteRoutes {
+		routeConf := &vyosRouteConf{}
+		exists, e := vyosShowConfig(vyosRoutePath(route.Network), routeConf)
+		if e != nil {
+			err = e
+			return
+		}
+
+		_, hasNexthop := routeConf.NextHop[route.Nexthop]
+		if exists && hasNexthop &&
+			routeConf.Description == vyosDescription {
+
+			err = vyosConfigure([]*vyosOp{
+				&vyosOp{
+					Op:   "delete",
+					Path: vyosRoutePath(route.Network),
+				},
+			})
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	err = route.Remove()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func vyosAddPorts() (err error) {
+	nexthop := state.GetLocalAddress()
+
+	iface := config.Config.Vyos.Interface
+	if iface == "" {
+		iface = vyosDefaultInterface
+	}
+
+	rules := map[string]*vyosNatRule{}
+	_, err = vyosShowConfig(
+		[]string{"nat", "destination", "rule"}, &rules)
+	if err != nil {
+		return
+	}
+
+	curPorts := map[string]bool{}
+	usedRules := map[int]bool{}
+	ops := []*vyosOp{}
+
+	for ruleNum, rule := range rules {
+		num, _ := strconv.Atoi(ruleNum)
+		usedRules[num] = true
+
+		if rule.Description != vyosDescription {
+			continue
+		}
+
+		valid := false
+		for _, port := range vyosPorts {
+			if rule.Protocol == port.Protocol &&
+				rule.Destination.Port == port.Port &&
+				rule.Translation.Address == nexthop &&
+				rule.InboundInterface.Name == iface &&
+				!curPorts[port.Protocol+port.Port] {
+
+				curPorts[port.Protocol+port.Port] = true
+				valid = true
+				break
+			}
+		}
+
+		if !valid {
+			ops = append(ops, &vyosOp{
+				Op:   "delete",
+				Path: []string{"nat", "destination", "rule", ruleNum},
+			})
+			delete(usedRules, num)
+		}
+	}
+
+	ruleNum := vyosRuleStart
+	for _, port := range vyosPorts {
+		if curPorts[port.Protocol+port.Port] {
+			continue
+		}
+
+		for usedRules[ruleNum] {
+			ruleNum += 1
+		}
+		usedRules[ruleNum] = true
+
+		rulePath := []string{
+			"nat", "destination", "rule", strconv.Itoa(ruleNum)}
+		for _, opt := range [][]string{
+			{"description", vyosDescription},
+			{"inbound-interface", "name", iface},
+			{"protocol", port.Protocol},
+			{"destination", "port", port.Port},
+			{"translation", "address", nexthop},
+		} {
+			ops = append(ops, &vyosOp{
+				Op:   "set",
+				Path: append(append([]string{}, rulePath...), opt...),
+			})
+		}
+	}
+
+	err = vyosConfigure(ops)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func VyosAddRoute(destination string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("%s", r))
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("vyos: VyOS add route recover")
+			return
+		}
+	}()
+
+	err = vyosAddRoute(destination)
+	return
+}
+
+func VyosDeleteRoute(route *routes.VyosRoute) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("%s", r))
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("vyos: VyOS delete route recover")
+			return
+		}
+	}()
+
+	err = vyosDeleteRoute(route)
+	return
+}
+
+func VyosAddPorts() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("%s", r))
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("vyos: VyOS add ports recover")
+			return
+		}
+	}()
+
+	err = vyosAddPorts()
+	return
+}
@@ -0,0 +1,165 @@
+package bgp
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/constants"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	sessions     = map[string]*session{}
+	sessionsLock = sync.Mutex{}
+	prefixes     = map[string]*net.IPNet{}
+	prefixesLock = sync.Mutex{}
+)
+
+func getPrefixes() (prfxs map[string]*net.IPNet) {
+	prfxs = map[string]*net.IPNet{}
+
+	prefixesLock.Lock()
+	for key, prefix := range prefixes {
+		prfxs[key] = prefix
+	}
+	prefixesLock.Unlock()
+
+	return
+}
+
+func SetPrefixes(networks []string) {
+	prfxs := map[string]*net.IPNet{}
+
+	for _, network := range networks {
+		_, prefix, err := net.ParseCIDR(network)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"network": network,
+				"error":   err,
+			}).Warn("bgp: Ignoring invalid network")
+			continue
+		}
+
+		prfxs[prefix.String()] = prefix
+	}
+
+	prefixesLock.Lock()
+	prefixes = prfxs
+	prefixesLock.Unlock()
+}
+
+func getSessionKey(neighbor *config.BgpNeighbor) string {
+	return fmt.Sprintf("%d-%s-%d-%s-%d-%d-%d-%d-%v",
+		config.Config.Bgp.Asn,
+		config.Config.Bgp.RouterId,
+		config.Config.Bgp.HoldTime,
+		neighbor.Address,
+		neighbor.Asn,
+		neighbor.Port,
+		neighbor.Med,
+		neighbor.LocalPref,
+		neighbor.Communities,
+	)
+}
+
+func update() {
+	newSessions := map[string]*config.BgpNeighbor{}
+
+	if config.Config.Bgp.Asn != 0 && !constants.Interrupt {
+		for _, neighbor := range config.Config.Bgp.Neighbors {
+			if neighbor == nil || neighbor.Address == "" ||
+				neighbor.Asn == 0 {
+
+				continue
+			}
+
+			newSessions[neighbor.Address] = neighbor
+		}
+	}
+
+	sessionsLock.Lock()
+	defer sessionsLock.Unlock()
+
+	for addr, sess := range sessions {
+		neighbor, ok := newSessions[addr]
+		if ok && sess.key == getSessionKey(neighbor) {
+			continue
+		}
+
+		if ok {
+			stopSession(sess, errCeaseAdminReset)
+		} else {
+			stopSession(sess, errCeaseAdminDown)
+		}
+		delete(sessions, addr)
+	}
+
+	for addr, neighbor := range newSessions {
+		if _, ok := sessions[addr]; ok {
+			continue
+		}
+
+		sess := &session{
+			key:      getSessionKey(neighbor),
+			asn:      config.Config.Bgp.Asn,
+			routerId: config.Config.Bgp.RouterId,
+			holdTime: config.Config.Bgp.HoldTime,
+			neighbor: *neighbor,
+			stop:     make(chan byte, 1),
+			done:     make(chan bool),
+		}
+		sessions[addr] = sess
+
+		logrus.WithFields(logrus.Fields{
+			"neighbor": neighbor.Address,
+			"asn":      neighbor.Asn,
+		}).Info("bgp: Starting neighbor session")
+
+		go sess.run()
+	}
+}
+
+func stopSession(sess *session, subcode byte) {
+	select {
+	case sess.stop <- subcode:
+	default:
+	}
+}
+
+func Stop() {
+	sessionsLock.Lock()
+	stopped := []*session{}
+	for addr, sess := range sessions {
+		stopSession(sess, errCeaseAdminDown)
+		stopped = append(stopped, sess)
+		delete(sessions, addr)
+	}
+	sessionsLock.Unlock()
+
+	timeout := time.After(stopTimeout)
+	for _, sess := range stopped {
+		select {
+		case <-sess.done:
+		case <-timeout:
+			logrus.Warn("bgp: Timeout waiting for sessions to stop")
+			return
+		}
+	}
+}
+
+func runUpdate() {
+	for {
+		if constants.Interrupt {
+			return
+		}
+		update()
+		time.Sleep(3 * time.Second)
+	}
+}
+
+func Init() {
+	go runUpdate()
+}
@@ -0,0 +1,63 @@
+package bgp
+
+import (
+	"time"
+)
+
+const (
+	defaultPort     = 179
+	defaultHoldTime = 90
+	connectTimeout  = 10 * time.Second
+	openTimeout     = 30 * time.Second
+	retryDelay      = 5 * time.Second
+	stopTimeout     = 3 * time.Second
+	writeTimeout    = 2 * time.Second
+	maxPrefixes     = 200
+
+	headerLen     = 19
+	maxMessageLen = 4096
+	version       = 4
+	asTrans       = 23456
+
+	msgOpen         = 1
+	msgUpdate       = 2
+	msgNotification = 3
+	msgKeepalive    = 4
+
+	capMultiprotocol = 1
+	capFourOctetAs   = 65
+
+	afiIpv4     = 1
+	afiIpv6     = 2
+	safiUnicast = 1
+
+	attrFlagOptional   = 0x80
+	attrFlagTransitive = 0x40
+	attrFlagExtended   = 0x10
+
+	attrOrigin      = 1
+	attrAsPath      = 2
+	attrNextHop     = 3
+	attrMed         = 4
+	attrLocalPref   = 5
+	attrCommunities = 8
+	attrMpReach     = 14
+	attrMpUnreach   = 15
+
+	originIgp     = 0
+	asPathSegment = 2
+
+	errOpen            = 2
+	errOpenBadPeerAs   = 2
+	errHoldTimer       = 4
+	errCease           = 6
+	errCeaseAdminDown  = 2
+	errCeaseAdminReset = 4
+)
+
+var communityNames = map[string]uint32{
+	"no-export":           0xFFFFFF01,
+	"no-advertise":        0xFFFFFF02,
+	"no-export-subconfed": 0xFFFFFF03,
+	"no-peer":             0xFFFFFF04,
+}
@@ -0,0 +1,386 @@
+package bgp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/errortypes"
+)
+
+type message struct {
+	Type byte
+	Body []byte
+}
+
+type openMessage struct {
+	Asn       uint32
+	HoldTime  uint16
+	RouterId  net.IP
+	FourOctet bool
+	Ipv4      bool
+	Ipv6      bool
+}
+
+type pathAttrs struct {
+	Asn         uint32
+	Ibgp        bool
+	FourOctet   bool
+	NextHop     net.IP
+	Med         uint32
+	LocalPref   uint32
+	Communities []uint32
+}
+
+func readMessage(reader io.Reader) (msg *message, err error) {
+	header := make([]byte, headerLen)
+
+	_, err = io.ReadFull(reader, header)
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "bgp: Failed to read message header"),
+		}
+		return
+	}
+
+	for i := 0; i < 16; i++ {
+		if header[i] != 0xff {
+			err = &errortypes.ParseError{
+				errors.New("bgp: Invalid message marker"),
+			}
+			return
+		}
+	}
+
+	length := int(binary.BigEndian.Uint16(header[16:18]))
+	if length < headerLen || length > maxMessageLen {
+		err = &errortypes.ParseError{
+			errors.Newf("bgp: Invalid message length %d", length),
+		}
+		return
+	}
+
+	msg = &message{
+		Type: header[18],
+		Body: make([]byte, length-headerLen),
+	}
+
+	_, err = io.ReadFull(reader, msg.Body)
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "bgp: Failed to read message body"),
+		}
+		return
+	}
+
+	return
+}
+
+func encodeMessage(typ byte, body []byte) []byte {
+	buf := make([]byte, headerLen, headerLen+len(body))
+	for i := 0; i < 16; i++ {
+		buf[i] = 0xff
+	}
+	binary.BigEndian.PutUint16(buf[16:18], uint16(headerLen+len(body)))
+	buf[18] = typ
+
+	return append(buf, body...)
+}
+
+func encodeOpen(asn uint32, holdTime uint16, routerId net.IP) []byte {
+	caps := &bytes.Buffer{}
+
+	for _, afi := range []uint16{afiIpv4, afiIpv6} {
+		caps.Write([]byte{capMultiprotocol, 4})
+		binary.Write(caps, binary.BigEndian, afi)
+		caps.Write([]byte{0, safiUnicast})
+	}
+
+	caps.Write([]byte{capFourOctetAs, 4})
+	binary.Write(caps, binary.BigEndian, asn)
+
+	body := &bytes.Buffer{}
+	body.WriteByte(version)
+
+	if asn > 0xffff {
+		binary.Write(body, binary.BigEndian, uint16(asTrans))
+	} else {
+		binary.Write(body, binary.BigEndian, uint16(asn))
+	}
+
+	binary.Write(body, binary.BigEndian, holdTime)
+	body.Write(routerId.To4())
+	body.WriteByte(byte(caps.Len() + 2))
+	body.WriteByte(2)
+	body.WriteByte(byte(caps.Len()))
+	body.Write(caps.Bytes())
+
+	return encodeMessage(msgOpen, body.Bytes())
+}
+
+func decodeOpen(body []byte) (open *openMessage, err error) {
+	if len(body) < 10 {
+		err = &errortypes.ParseError{
+			errors.New("bgp: Open message too short"),
+		}
+		return
+	}
+
+	if body[0] != version {
+		err = &errortypes.ParseError{
+			errors.Newf("bgp: Unsupported version %d", body[0]),
+		}
+		return
+	}
+
+	open = &openMessage{
+		Asn:      uint32(binary.BigEndian.Uint16(body[1:3])),
+		HoldTime: binary.BigEndian.Uint16(body[3:5]),
+		RouterId: net.IP(body[5:9]),
+	}
+
+	paramsLen := int(body[9])
+	params := body[10:]
+	if len(params) < paramsLen {
+		err = &errortypes.ParseError{
+			errors.New("bgp: Open parameters truncated"),
+		}
+		return
+	}
+	params = params[:paramsLen]
+
+	multiprotocol := false
+
+	for len(params) >= 2 {
+		paramType := params[0]
+		paramLen := int(params[1])
+		if len(params) < 2+paramLen {
+			break
+		}
+		param := params[2 : 2+paramLen]
+		params = params[2+paramLen:]
+
+		if paramType != 2 {
+			continue
+		}
+
+		for len(param) >= 2 {
+			capCode := param[0]
+			capLen := int(param[1])
+			if len(param) < 2+capLen {
+				break
+			}
+			capData := param[2 : 2+capLen]
+			param = param[2+capLen:]
+
+			if capCode == capFourOctetAs && capLen == 4 {
+				open.FourOctet = true
+				open.Asn = binary.BigEndian.Uint32(capData)
+			} else if capCode == capMultiprotocol && capLen == 4 {
+				multiprotocol = true
+
+				if capData[3] != safiUnicast {
+					continue
+				}
+
+				switch binary.BigEndian.Uint16(capData[0:2]) {
+				case afiIpv4:
+					open.Ipv4 = true
+				case afiIpv6:
+					open.Ipv6 = true
+				}
+			}
+		}
+	}
+
+	if !multiprotocol {
+		open.Ipv4 = true
+	}
+
+	return
+}
+
+func encodeKeepalive() []byte {
+	return encodeMessage(msgKeepalive, nil)
+}
+
+func encodeNotification(code, subcode byte) []byte {
+	return encodeMessage(msgNotification, []byte{code, subcode})
+}
+
+func encodePrefix(buf *bytes.Buffer, prefix *net.IPNet) {
+	ones, _ := prefix.Mask.Size()
+
+	ip := prefix.IP.To4()
+	if ip == nil {
+		ip = prefix.IP.To16()
+	}
+
+	buf.WriteByte(byte(ones))
+	buf.Write(ip[:(ones+7)/8])
+}
+
+func encodeAttr(buf *bytes.Buffer, flags, typ byte, data []byte) {
+	if len(data) > 255 {
+		buf.Write([]byte{flags | attrFlagExtended, typ})
+		binary.Write(buf, binary.BigEndian, uint16(len(data)))
+	} else {
+		buf.Write([]byte{flags, typ, byte(len(data))})
+	}
+	buf.Write(data)
+}
+
+func encodeAttrs(buf *bytes.Buffer, attrs *pathAttrs) {
+	encodeAttr(buf, attrFlagTransitive, attrOrigin, []byte{originIgp})
+
+	asPath := &bytes.Buffer{}
+	if !attrs.Ibgp {
+		asPath.Write([]byte{asPathSegment, 1})
+		if attrs.FourOctet {
+			binary.Write(asPath, binary.BigEndian, attrs.Asn)
+		} else if attrs.Asn > 0xffff {
+			binary.Write(asPath, binary.BigEndian, uint16(asTrans))
+		} else {
+			binary.Write(asPath, binary.BigEndian, uint16(attrs.Asn))
+		}
+	}
+	encodeAttr(buf, attrFlagTransitive, attrAsPath, asPath.Bytes())
+
+	if attrs.Med != 0 {
+		med := make([]byte, 4)
+		binary.BigEndian.PutUint32(med, attrs.Med)
+		encodeAttr(buf, attrFlagOptional, attrMed, med)
+	}
+
+	if attrs.Ibgp {
+		localPref := attrs.LocalPref
+		if localPref == 0 {
+			localPref = 100
+		}
+
+		pref := make([]byte, 4)
+		binary.BigEndian.PutUint32(pref, localPref)
+		encodeAttr(buf, attrFlagTransitive, attrLocalPref, pref)
+	}
+
+	if len(attrs.Communities) > 0 {
+		comms := &bytes.Buffer{}
+		for _, comm := range attrs.Communities {
+			binary.Write(comms, binary.BigEndian, comm)
+		}
+		encodeAttr(buf, attrFlagOptional|attrFlagTransitive,
+			attrCommunities, comms.Bytes())
+	}
+}
+
+func encodeUpdate4(withdrawn, announced []*net.IPNet,
+	attrs *pathAttrs) []byte {
+
+	withdrawnBuf := &bytes.Buffer{}
+	for _, prefix := range withdrawn {
+		encodePrefix(withdrawnBuf, prefix)
+	}
+
+	attrsBuf := &bytes.Buffer{}
+	nlriBuf := &bytes.Buffer{}
+	if len(announced) > 0 {
+		encodeAttrs(attrsBuf, attrs)
+		encodeAttr(attrsBuf, attrFlagTransitive, attrNextHop,
+			attrs.NextHop.To4())
+
+		for _, prefix := range announced {
+			encodePrefix(nlriBuf, prefix)
+		}
+	}
+
+	body := &bytes.Buffer{}
+	binary.Write(body, binary.BigEndian, uint16(withdrawnBuf.Len()))
+	body.Write(withdrawnBuf.Bytes())
+	binary.Write(body, binary.BigEndian, uint16(attrsBuf.Len()))
+	body.Write(attrsBuf.Bytes())
+	body.Write(nlriBuf.Bytes())
+
+	return encodeMessage(msgUpdate, body.Bytes())
+}
+
+func encodeUpdate6(withdrawn, announced []*net.IPNet,
+	attrs *pathAttrs) []byte {
+
+	attrsBuf := &bytes.Buffer{}
+
+	if len(announced) > 0 {
+		encodeAttrs(attrsBuf, attrs)
+
+		reach := &bytes.Buffer{}
+		binary.Write(reach, binary.BigEndian, uint16(afiIpv6))
+		reach.WriteByte(safiUnicast)
+		reach.WriteByte(16)
+		reach.Write(attrs.NextHop.To16())
+		reach.WriteByte(0)
+		for _, prefix := range announced {
+			encodePrefix(reach, prefix)
+		}
+
+		encodeAttr(attrsBuf, attrFlagOptional, attrMpReach, reach.Bytes())
+	}
+
+	if len(withdrawn) > 0 {
+		unreach := &bytes.Buffer{}
+		binary.Write(unreach, binary.BigEndian, uint16(afiIpv6))
+		unreach.WriteByte(safiUnicast)
+		for _, prefix := range withdrawn {
+			encodePrefix(unreach, prefix)
+		}
+
+		encodeAttr(attrsBuf, attrFlagOptional, attrMpUnreach,
+			unreach.Bytes())
+	}
+
+	body := &bytes.Buffer{}
+	binary.Write(body, binary.BigEndian, uint16(0))
+	binary.Write(body, binary.BigEndian, uint16(attrsBuf.Len()))
+	body.Write(attrsBuf.Bytes())
+
+	return encodeMessage(msgUpdate, body.Bytes())
+}
+
+func ParseCommunity(community string) (value uint32, err error) {
+	community = strings.ToLower(strings.TrimSpace(community))
+
+	if val, ok := communityNames[community]; ok {
+		value = val
+		return
+	}
+
+	parts := strings.Split(community, ":")
+	if len(parts) != 2 {
+		err = &errortypes.ParseError{
+			errors.Newf("bgp: Invalid community '%s'", community),
+		}
+		return
+	}
+
+	high, err := strconv.ParseUint(parts[0], 10, 16)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrapf(err, "bgp: Invalid community '%s'", community),
+		}
+		return
+	}
+
+	low, err := strconv.ParseUint(parts[1], 10, 16)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrapf(err, "bgp: Invalid community '%s'", community),
+		}
+		return
+	}
+
+	value = uint32(high)<<16 | uint32(low)
+
+	return
+}
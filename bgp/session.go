@@ -0,0 +1,441 @@
+package bgp
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/errortypes"
+	"github.com/pritunl/pritunl-link/state"
+	"github.com/sirupsen/logrus"
+)
+
+type session struct {
+	key        string
+	asn        uint32
+	routerId   string
+	holdTime   int
+	neighbor   config.BgpNeighbor
+	conn       net.Conn
+	fourOctet  bool
+	ipv4       bool
+	ipv6       bool
+	ibgp       bool
+	nextHop    net.IP
+	nextHop6   net.IP
+	advertised map[string]*net.IPNet
+	stop       chan byte
+	done       chan bool
+}
+
+func (s *session) getAttrs(nextHop net.IP) (attrs *pathAttrs) {
+	attrs = &pathAttrs{
+		Asn:       s.asn,
+		Ibgp:      s.ibgp,
+		FourOctet: s.fourOctet,
+		NextHop:   nextHop,
+		Med:       s.neighbor.Med,
+		LocalPref: s.neighbor.LocalPref,
+	}
+
+	for _, community := range s.neighbor.Communities {
+		value, err := ParseCommunity(community)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"neighbor":  s.neighbor.Address,
+				"community": community,
+				"error":     err,
+			}).Warn("bgp: Ignoring invalid community")
+			continue
+		}
+
+		attrs.Communities = append(attrs.Communities, value)
+	}
+
+	return
+}
+
+func (s *session) write(data []byte) (err error) {
+	s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+
+	_, err = s.conn.Write(data)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "bgp: Failed to write message"),
+		}
+		return
+	}
+
+	return
+}
+
+func (s *session) connect() (holdTime time.Duration, err error) {
+	port := s.neighbor.Port
+	if port == 0 {
+		port = defaultPort
+	}
+
+	routerId := net.ParseIP(s.routerId).To4()
+	if routerId == nil {
+		routerId = net.ParseIP(state.GetLocalAddress()).To4()
+	}
+	if routerId == nil {
+		err = &errortypes.ParseError{
+			errors.New("bgp: Router id unavailable"),
+		}
+		return
+	}
+
+	localHoldTime := s.holdTime
+	if localHoldTime == 0 {
+		localHoldTime = defaultHoldTime
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(
+		s.neighbor.Address, strconv.Itoa(port)), connectTimeout)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "bgp: Failed to connect to neighbor"),
+		}
+		return
+	}
+	s.conn = conn
+
+	conn.SetDeadline(time.Now().Add(openTimeout))
+
+	err = s.write(encodeOpen(s.asn, uint16(localHoldTime), routerId))
+	if err != nil {
+		return
+	}
+
+	msg, err := readMessage(conn)
+	if err != nil {
+		return
+	}
+
+	if msg.Type == msgNotification {
+		err = notificationError(msg.Body)
+		return
+	}
+
+	if msg.Type != msgOpen {
+		err = &errortypes.ParseError{
+			errors.Newf("bgp: Unexpected message type %d", msg.Type),
+		}
+		return
+	}
+
+	open, err := decodeOpen(msg.Body)
+	if err != nil {
+		return
+	}
+
+	if open.Asn != s.neighbor.Asn {
+		s.write(encodeNotification(errOpen, errOpenBadPeerAs))
+
+		err = &errortypes.ParseError{
+			errors.Newf("bgp: Neighbor asn %d does not match %d",
+				open.Asn, s.neighbor.Asn),
+		}
+		return
+	}
+
+	s.fourOctet = open.FourOctet
+	s.ipv4 = open.Ipv4
+	s.ipv6 = open.Ipv6
+	s.ibgp = open.Asn == s.asn
+
+	negHoldTime := localHoldTime
+	if int(open.HoldTime) < negHoldTime {
+		negHoldTime = int(open.HoldTime)
+	}
+	holdTime = time.Duration(negHoldTime) * time.Second
+
+	err = s.write(encodeKeepalive())
+	if err != nil {
+		return
+	}
+
+	for {
+		msg, err = readMessage(conn)
+		if err != nil {
+			return
+		}
+
+		if msg.Type == msgNotification {
+			err = notificationError(msg.Body)
+			return
+		}
+
+		if msg.Type == msgKeepalive {
+			break
+		}
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	return
+}
+
+func (s *session) read(conn net.Conn, holdTime time.Duration,
+	errs chan error) {
+
+	for {
+		if holdTime != 0 {
+			conn.SetReadDeadline(time.Now().Add(holdTime))
+		}
+
+		msg, err := readMessage(conn)
+		if err != nil {
+			errs <- err
+			return
+		}
+
+		if msg.Type == msgNotification {
+			errs <- notificationError(msg.Body)
+			return
+		}
+	}
+}
+
+func (s *session) sendUpdates(withdrawn, announced []*net.IPNet,
+	nextHop net.IP, ipv6 bool) (err error) {
+
+	attrs := s.getAttrs(nextHop)
+
+	for len(withdrawn) > 0 || len(announced) > 0 {
+		withdrawnChunk := withdrawn
+		if len(withdrawnChunk) > maxPrefixes {
+			withdrawnChunk = withdrawnChunk[:maxPrefixes]
+		}
+		withdrawn = withdrawn[len(withdrawnChunk):]
+
+		announcedChunk := announced
+		if len(announcedChunk) > maxPrefixes-len(withdrawnChunk) {
+			announcedChunk = announcedChunk[:maxPrefixes-
+				len(withdrawnChunk)]
+		}
+		announced = announced[len(announcedChunk):]
+
+		if ipv6 {
+			err = s.write(encodeUpdate6(
+				withdrawnChunk, announcedChunk, attrs))
+		} else {
+			err = s.write(encodeUpdate4(
+				withdrawnChunk, announcedChunk, attrs))
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func (s *session) sync() (err error) {
+	nextHop := net.ParseIP(config.Config.Bgp.NextHop).To4()
+	if nextHop == nil {
+		nextHop = net.ParseIP(state.GetLocalAddress()).To4()
+	}
+
+	nextHop6 := net.ParseIP(config.Config.Bgp.NextHop6)
+	if nextHop6 == nil {
+		nextHop6 = net.ParseIP(state.GetAddress6())
+	}
+	if nextHop6 != nil && nextHop6.To4() != nil {
+		nextHop6 = nil
+	}
+
+	if !s.ipv4 {
+		nextHop = nil
+	}
+	if !s.ipv6 {
+		nextHop6 = nil
+	}
+
+	reannounce4 := !nextHop.Equal(s.nextHop)
+	reannounce6 := !nextHop6.Equal(s.nextHop6)
+	s.nextHop = nextHop
+	s.nextHop6 = nextHop6
+
+	desired := getPrefixes()
+
+	withdrawn4 := []*net.IPNet{}
+	announced4 := []*net.IPNet{}
+	withdrawn6 := []*net.IPNet{}
+	announced6 := []*net.IPNet{}
+
+	for key, prefix := range s.advertised {
+		ipv6 := prefix.IP.To4() == nil
+
+		if _, ok := desired[key]; ok &&
+			((!ipv6 && nextHop != nil) || (ipv6 && nextHop6 != nil)) {
+
+			continue
+		}
+
+		if ipv6 {
+			withdrawn6 = append(withdrawn6, prefix)
+		} else {
+			withdrawn4 = append(withdrawn4, prefix)
+		}
+		delete(s.advertised, key)
+	}
+
+	for key, prefix := range desired {
+		ipv6 := prefix.IP.To4() == nil
+
+		if ipv6 {
+			if nextHop6 == nil {
+				continue
+			}
+
+			if _, ok := s.advertised[key]; ok && !reannounce6 {
+				continue
+			}
+
+			announced6 = append(announced6, prefix)
+		} else {
+			if nextHop == nil {
+				continue
+			}
+
+			if _, ok := s.advertised[key]; ok && !reannounce4 {
+				continue
+			}
+
+			announced4 = append(announced4, prefix)
+		}
+		s.advertised[key] = prefix
+	}
+
+	if len(withdrawn4) > 0 || len(announced4) > 0 {
+		err = s.sendUpdates(withdrawn4, announced4, nextHop, false)
+		if err != nil {
+			return
+		}
+	}
+
+	if len(withdrawn6) > 0 || len(announced6) > 0 {
+		err = s.sendUpdates(withdrawn6, announced6, nextHop6, true)
+		if err != nil {
+			return
+		}
+	}
+
+	if len(withdrawn4)+len(withdrawn6)+
+		len(announced4)+len(announced6) > 0 {
+
+		logrus.WithFields(logrus.Fields{
+			"neighbor":  s.neighbor.Address,
+			"announced": len(announced4) + len(announced6),
+			"withdrawn": len(withdrawn4) + len(withdrawn6),
+		}).Info("bgp: Updated neighbor routes")
+	}
+
+	return
+}
+
+func (s *session) serve() (stopped bool, err error) {
+	s.advertised = map[string]*net.IPNet{}
+	s.nextHop = nil
+	s.nextHop6 = nil
+
+	holdTime, err := s.connect()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"neighbor":  s.neighbor.Address,
+		"asn":       s.neighbor.Asn,
+		"hold_time": holdTime.Seconds(),
+	}).Info("bgp: Session established")
+
+	errs := make(chan error, 1)
+	go s.read(s.conn, holdTime, errs)
+
+	keepalive := holdTime / 3
+	lastKeepalive := time.Now()
+
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case subcode := <-s.stop:
+			stopped = true
+			s.write(encodeNotification(errCease, subcode))
+			return
+		case err = <-errs:
+			return
+		case <-ticker.C:
+			err = s.sync()
+			if err != nil {
+				return
+			}
+
+			if keepalive != 0 && time.Since(lastKeepalive) >= keepalive {
+				err = s.write(encodeKeepalive())
+				if err != nil {
+					return
+				}
+				lastKeepalive = time.Now()
+			}
+		}
+	}
+}
+
+func (s *session) run() {
+	defer close(s.done)
+
+	for {
+		stopped, err := s.serve()
+		if s.conn != nil {
+			s.conn.Close()
+			s.conn = nil
+		}
+
+		if stopped {
+			return
+		}
+
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"neighbor": s.neighbor.Address,
+				"asn":      s.neighbor.Asn,
+				"error":    err,
+			}).Warn("bgp: Neighbor session failed")
+		}
+
+		select {
+		case <-s.stop:
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+func notificationError(body []byte) (err error) {
+	code := 0
+	subcode := 0
+	if len(body) >= 2 {
+		code = int(body[0])
+		subcode = int(body[1])
+	}
+
+	if code == errHoldTimer {
+		err = &errortypes.RequestError{
+			errors.New("bgp: Neighbor hold timer expired"),
+		}
+		return
+	}
+
+	err = &errortypes.RequestError{
+		errors.Newf("bgp: Neighbor notification %d/%d", code, subcode),
+	}
+
+	return
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/bgp"
 	"github.com/pritunl/pritunl-link/config"
 	"github.com/pritunl/pritunl-link/errortypes"
 	"github.com/pritunl/pritunl-link/ipsec"
@@ -90,10 +91,14 @@ func cleanup(uri string) (err error) {
 func CleanUp() {
 	uris := config.Config.Uris
 
+	bgp.Stop()
 	iptables.ClearIpTables()
 	ipsec.DelDirectRoute()
+	ipsec.ClearProxy()
+	ipsec.ClearShaping()
 	ipsec.StopTunnel()
 	ipsec.StopWg()
+	ipsec.ClearXfrm()
 
 	for _, uri := range uris {
 		go cleanup(uri)
@@ -0,0 +1,282 @@
+package cmd
+
+import (
+	"net"
+	"regexp"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/errortypes"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	aclIdReg   = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+	aclPortReg = regexp.MustCompile(`^[0-9]+(:[0-9]+)?$`)
+)
+
+func getAcl(id string) (acl *config.AclData, err error) {
+	for _, a := range config.Config.Acls {
+		if a.Id == id {
+			acl = a
+			return
+		}
+	}
+
+	err = &errortypes.ParseError{
+		errors.Newf("cmd.acl: Unknown acl '%s'", id),
+	}
+
+	return
+}
+
+func parseAclNetwork(network string) (parsed string, err error) {
+	if network == "" {
+		return
+	}
+
+	_, ipNet, e := net.ParseCIDR(network)
+	if e == nil {
+		parsed = ipNet.String()
+		return
+	}
+
+	ip := net.ParseIP(network)
+	if ip == nil {
+		err = &errortypes.ParseError{
+			errors.Newf("cmd.acl: Invalid network '%s'", network),
+		}
+		return
+	}
+
+	if ip.To4() != nil {
+		parsed = ip.String() + "/32"
+	} else {
+		parsed = ip.String() + "/128"
+	}
+
+	return
+}
+
+func AclAdd(id, action string) (err error) {
+	if !aclIdReg.MatchString(id) {
+		err = &errortypes.ParseError{
+			errors.Newf("cmd.acl: Invalid acl id '%s'", id),
+		}
+		return
+	}
+
+	if action != "allow" && action != "deny" {
+		err = &errortypes.ParseError{
+			errors.Newf("cmd.acl: Invalid acl action '%s'", action),
+		}
+		return
+	}
+
+	acl, e := getAcl(id)
+	if e != nil {
+		acl = &config.AclData{
+			Id: id,
+		}
+		config.Config.Acls = append(config.Config.Acls, acl)
+	}
+	acl.Action = action
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":     acl.Id,
+		"action": acl.Action,
+	}).Info("cmd.acl: Added acl")
+
+	return
+}
+
+func AclRemove(id string) (err error) {
+	acls := []*config.AclData{}
+	for _, acl := range config.Config.Acls {
+		if acl.Id != id {
+			acls = append(acls, acl)
+		}
+	}
+	config.Config.Acls = acls
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id": id,
+	}).Info("cmd.acl: Removed acl")
+
+	return
+}
+
+func AclState(id, stateId string) (err error) {
+	acl, err := getAcl(id)
+	if err != nil {
+		return
+	}
+
+	acl.State = stateId
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":    acl.Id,
+		"state": acl.State,
+	}).Info("cmd.acl: Set acl state")
+
+	return
+}
+
+func AclLink(id, linkId string) (err error) {
+	acl, err := getAcl(id)
+	if err != nil {
+		return
+	}
+
+	acl.Link = linkId
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":   acl.Id,
+		"link": acl.Link,
+	}).Info("cmd.acl: Set acl link")
+
+	return
+}
+
+func AclSource(id, source string) (err error) {
+	acl, err := getAcl(id)
+	if err != nil {
+		return
+	}
+
+	source, err = parseAclNetwork(source)
+	if err != nil {
+		return
+	}
+
+	acl.Source = source
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":     acl.Id,
+		"source": acl.Source,
+	}).Info("cmd.acl: Set acl source")
+
+	return
+}
+
+func AclDestination(id, destination string) (err error) {
+	acl, err := getAcl(id)
+	if err != nil {
+		return
+	}
+
+	destination, err = parseAclNetwork(destination)
+	if err != nil {
+		return
+	}
+
+	acl.Destination = destination
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":          acl.Id,
+		"destination": acl.Destination,
+	}).Info("cmd.acl: Set acl destination")
+
+	return
+}
+
+func AclProtocol(id, protocol string) (err error) {
+	acl, err := getAcl(id)
+	if err != nil {
+		return
+	}
+
+	switch protocol {
+	case "", "tcp", "udp", "icmp", "ipv6-icmp":
+		break
+	default:
+		err = &errortypes.ParseError{
+			errors.Newf("cmd.acl: Invalid acl protocol '%s'", protocol),
+		}
+		return
+	}
+
+	acl.Protocol = protocol
+	if protocol != "tcp" && protocol != "udp" {
+		acl.Port = ""
+	}
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":       acl.Id,
+		"protocol": acl.Protocol,
+	}).Info("cmd.acl: Set acl protocol")
+
+	return
+}
+
+func AclPort(id, port string) (err error) {
+	acl, err := getAcl(id)
+	if err != nil {
+		return
+	}
+
+	if port != "" {
+		if acl.Protocol != "tcp" && acl.Protocol != "udp" {
+			err = &errortypes.ParseError{
+				errors.New("cmd.acl: Acl port requires tcp or udp protocol"),
+			}
+			return
+		}
+
+		if !aclPortReg.MatchString(port) {
+			err = &errortypes.ParseError{
+				errors.Newf("cmd.acl: Invalid acl port '%s'", port),
+			}
+			return
+		}
+	}
+
+	acl.Port = port
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":   acl.Id,
+		"port": acl.Port,
+	}).Info("cmd.acl: Set acl port")
+
+	return
+}
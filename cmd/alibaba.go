@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/sirupsen/logrus"
+)
+
+func AlibabaRegion(region string) (err error) {
+	config.Config.Alibaba.Region = region
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"region": config.Config.Alibaba.Region,
+	}).Info("cmd.alibaba: Set Alibaba region")
+
+	return
+}
+
+func AlibabaVpcId(vpcId string) (err error) {
+	config.Config.Alibaba.VpcId = vpcId
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"vpc_id": config.Config.Alibaba.VpcId,
+	}).Info("cmd.alibaba: Set Alibaba VPC id")
+
+	return
+}
+
+func AlibabaInstanceId(instanceId string) (err error) {
+	config.Config.Alibaba.InstanceId = instanceId
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"instance_id": config.Config.Alibaba.InstanceId,
+	}).Info("cmd.alibaba: Set Alibaba instance id")
+
+	return
+}
+
+func AlibabaAccessKeyId(accessKeyId string) (err error) {
+	config.Config.Alibaba.AccessKeyId = accessKeyId
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"access_key_id": config.Config.Alibaba.AccessKeyId,
+	}).Info("cmd.alibaba: Set Alibaba access key id")
+
+	return
+}
+
+func AlibabaAccessKeySecret(accessKeySecret string) (err error) {
+	config.Config.Alibaba.AccessKeySecret = accessKeySecret
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"access_key_secret": config.Config.Alibaba.AccessKeySecret,
+	}).Info("cmd.alibaba: Set Alibaba access key secret")
+
+	return
+}
@@ -0,0 +1,309 @@
+package cmd
+
+import (
+	"strconv"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/bgp"
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/errortypes"
+	"github.com/sirupsen/logrus"
+)
+
+func parseUint32(val string) (n uint32, err error) {
+	parsed, err := strconv.ParseUint(val, 10, 32)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrapf(err, "cmd.bgp: Invalid number '%s'", val),
+		}
+		return
+	}
+
+	n = uint32(parsed)
+
+	return
+}
+
+func getBgpNeighbor(address string) (neighbor *config.BgpNeighbor,
+	err error) {
+
+	for _, nbr := range config.Config.Bgp.Neighbors {
+		if nbr.Address == address {
+			neighbor = nbr
+			return
+		}
+	}
+
+	err = &errortypes.ParseError{
+		errors.Newf("cmd.bgp: Unknown neighbor '%s'", address),
+	}
+
+	return
+}
+
+func BgpAsn(asnStr string) (err error) {
+	asn, err := parseUint32(asnStr)
+	if err != nil {
+		return
+	}
+
+	config.Config.Bgp.Asn = asn
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"asn": config.Config.Bgp.Asn,
+	}).Info("cmd.bgp: Set BGP asn")
+
+	return
+}
+
+func BgpRouterId(routerId string) (err error) {
+	config.Config.Bgp.RouterId = routerId
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"router_id": config.Config.Bgp.RouterId,
+	}).Info("cmd.bgp: Set BGP router id")
+
+	return
+}
+
+func BgpHoldTime(holdTimeStr string) (err error) {
+	holdTime, err := strconv.Atoi(holdTimeStr)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrapf(err, "cmd.bgp: Invalid hold time '%s'",
+				holdTimeStr),
+		}
+		return
+	}
+
+	config.Config.Bgp.HoldTime = holdTime
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"hold_time": config.Config.Bgp.HoldTime,
+	}).Info("cmd.bgp: Set BGP hold time")
+
+	return
+}
+
+func BgpNextHop(nextHop string) (err error) {
+	config.Config.Bgp.NextHop = nextHop
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"next_hop": config.Config.Bgp.NextHop,
+	}).Info("cmd.bgp: Set BGP next hop")
+
+	return
+}
+
+func BgpNextHop6(nextHop6 string) (err error) {
+	config.Config.Bgp.NextHop6 = nextHop6
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"next_hop6": config.Config.Bgp.NextHop6,
+	}).Info("cmd.bgp: Set BGP IPv6 next hop")
+
+	return
+}
+
+func BgpNeighborAdd(address, asnStr string) (err error) {
+	asn, err := parseUint32(asnStr)
+	if err != nil {
+		return
+	}
+
+	neighbor, e := getBgpNeighbor(address)
+	if e != nil {
+		neighbor = &config.BgpNeighbor{
+			Address:     address,
+			Communities: []string{},
+		}
+		config.Config.Bgp.Neighbors = append(
+			config.Config.Bgp.Neighbors, neighbor)
+	}
+	neighbor.Asn = asn
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"address": neighbor.Address,
+		"asn":     neighbor.Asn,
+	}).Info("cmd.bgp: Added BGP neighbor")
+
+	return
+}
+
+func BgpNeighborRemove(address string) (err error) {
+	neighbors := []*config.BgpNeighbor{}
+	for _, neighbor := range config.Config.Bgp.Neighbors {
+		if neighbor.Address != address {
+			neighbors = append(neighbors, neighbor)
+		}
+	}
+	config.Config.Bgp.Neighbors = neighbors
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"address": address,
+	}).Info("cmd.bgp: Removed BGP neighbor")
+
+	return
+}
+
+func BgpNeighborPort(address, portStr string) (err error) {
+	neighbor, err := getBgpNeighbor(address)
+	if err != nil {
+		return
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrapf(err, "cmd.bgp: Invalid port '%s'", portStr),
+		}
+		return
+	}
+
+	neighbor.Port = port
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"address": neighbor.Address,
+		"port":    neighbor.Port,
+	}).Info("cmd.bgp: Set BGP neighbor port")
+
+	return
+}
+
+func BgpNeighborMed(address, medStr string) (err error) {
+	neighbor, err := getBgpNeighbor(address)
+	if err != nil {
+		return
+	}
+
+	med, err := parseUint32(medStr)
+	if err != nil {
+		return
+	}
+
+	neighbor.Med = med
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"address": neighbor.Address,
+		"med":     neighbor.Med,
+	}).Info("cmd.bgp: Set BGP neighbor med")
+
+	return
+}
+
+func BgpNeighborLocalPref(address, prefStr string) (err error) {
+	neighbor, err := getBgpNeighbor(address)
+	if err != nil {
+		return
+	}
+
+	pref, err := parseUint32(prefStr)
+	if err != nil {
+		return
+	}
+
+	neighbor.LocalPref = pref
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"address":    neighbor.Address,
+		"local_pref": neighbor.LocalPref,
+	}).Info("cmd.bgp: Set BGP neighbor local preference")
+
+	return
+}
+
+func BgpCommunityAdd(address, community string) (err error) {
+	neighbor, err := getBgpNeighbor(address)
+	if err != nil {
+		return
+	}
+
+	_, err = bgp.ParseCommunity(community)
+	if err != nil {
+		return
+	}
+
+	neighbor.Communities = append(neighbor.Communities, community)
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"address":     neighbor.Address,
+		"communities": neighbor.Communities,
+	}).Info("cmd.bgp: Added BGP neighbor community")
+
+	return
+}
+
+func BgpCommunityClear(address string) (err error) {
+	neighbor, err := getBgpNeighbor(address)
+	if err != nil {
+		return
+	}
+
+	neighbor.Communities = []string{}
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"address": neighbor.Address,
+	}).Info("cmd.bgp: Cleared BGP neighbor communities")
+
+	return
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/errortypes"
+	"github.com/pritunl/pritunl-link/ipsec"
+	"github.com/sirupsen/logrus"
+)
+
+func validCipherProfile(profile string) (err error) {
+	if !ipsec.ValidCipherProfile(profile) {
+		err = &errortypes.ParseError{
+			errors.Newf("cmd.cipher: Invalid cipher profile '%s'", profile),
+		}
+		return
+	}
+
+	err = ipsec.CheckCipherProfile(profile)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func CipherProfile(profile string) (err error) {
+	if profile != "" {
+		err = validCipherProfile(profile)
+		if err != nil {
+			return
+		}
+	}
+
+	config.Config.CipherProfile = profile
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"cipher_profile": config.Config.CipherProfile,
+	}).Info("cmd.cipher: Set cipher profile")
+
+	return
+}
+
+func CipherProfileSet(stateId, profile string) (err error) {
+	err = validCipherProfile(profile)
+	if err != nil {
+		return
+	}
+
+	profiles := []*config.CipherData{}
+	for _, cipherProfile := range config.Config.CipherProfiles {
+		if cipherProfile.State != stateId {
+			profiles = append(profiles, cipherProfile)
+		}
+	}
+	profiles = append(profiles, &config.CipherData{
+		State:   stateId,
+		Profile: profile,
+	})
+	config.Config.CipherProfiles = profiles
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"state":          stateId,
+		"cipher_profile": profile,
+	}).Info("cmd.cipher: Set link cipher profile")
+
+	return
+}
+
+func CipherProfileRemove(stateId string) (err error) {
+	profiles := []*config.CipherData{}
+	for _, cipherProfile := range config.Config.CipherProfiles {
+		if cipherProfile.State != stateId {
+			profiles = append(profiles, cipherProfile)
+		}
+	}
+	config.Config.CipherProfiles = profiles
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"state": stateId,
+	}).Info("cmd.cipher: Removed link cipher profile")
+
+	return
+}
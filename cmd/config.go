@@ -1,7 +1,11 @@
 package cmd
 
 import (
+	"net"
+
+	"github.com/dropbox/godropbox/errors"
 	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/errortypes"
 	"github.com/sirupsen/logrus"
 )
 
@@ -239,3 +243,295 @@ func ClearCustomOptions() (err error) {
 
 	return
 }
+
+func ProxyInterface(iface string) (err error) {
+	config.Config.ProxyInterface = iface
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"proxy_interface": config.Config.ProxyInterface,
+	}).Info("cmd.config: Proxy interface set")
+
+	return
+}
+
+func AddProxyNetwork(network string) (err error) {
+	for _, proxyNetwork := range config.Config.ProxyNetworks {
+		if proxyNetwork == network {
+			return
+		}
+	}
+
+	config.Config.ProxyNetworks = append(
+		config.Config.ProxyNetworks, network)
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"proxy_networks": config.Config.ProxyNetworks,
+	}).Info("cmd.config: Added proxy network")
+
+	return
+}
+
+func RemoveProxyNetwork(network string) (err error) {
+	proxyNetworks := []string{}
+	for _, proxyNetwork := range config.Config.ProxyNetworks {
+		if proxyNetwork != network {
+			proxyNetworks = append(proxyNetworks, proxyNetwork)
+		}
+	}
+	config.Config.ProxyNetworks = proxyNetworks
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"proxy_networks": config.Config.ProxyNetworks,
+	}).Info("cmd.config: Removed proxy network")
+
+	return
+}
+
+func AddNetmap(network, mapped string) (err error) {
+	_, realNet, err := net.ParseCIDR(network)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "cmd.config: Failed to parse network"),
+		}
+		return
+	}
+
+	_, mappedNet, err := net.ParseCIDR(mapped)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "cmd.config: Failed to parse mapped network"),
+		}
+		return
+	}
+
+	realOnes, realBits := realNet.Mask.Size()
+	mappedOnes, mappedBits := mappedNet.Mask.Size()
+	if realOnes != mappedOnes || realBits != mappedBits {
+		err = &errortypes.ParseError{
+			errors.New("cmd.config: Network and mapped network " +
+				"must be the same size"),
+		}
+		return
+	}
+
+	netmaps := []*config.NetmapData{}
+	for _, netmap := range config.Config.Netmaps {
+		if netmap.Mapped != mappedNet.String() {
+			netmaps = append(netmaps, netmap)
+		}
+	}
+	netmaps = append(netmaps, &config.NetmapData{
+		Network: realNet.String(),
+		Mapped:  mappedNet.String(),
+	})
+	config.Config.Netmaps = netmaps
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"network": realNet.String(),
+		"mapped":  mappedNet.String(),
+	}).Info("cmd.config: Added netmap")
+
+	return
+}
+
+func RemoveNetmap(mapped string) (err error) {
+	netmaps := []*config.NetmapData{}
+	for _, netmap := range config.Config.Netmaps {
+		if netmap.Mapped != mapped {
+			netmaps = append(netmaps, netmap)
+		}
+	}
+	config.Config.Netmaps = netmaps
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"mapped": mapped,
+	}).Info("cmd.config: Removed netmap")
+
+	return
+}
+
+func MasqueradeOn() (err error) {
+	config.Config.Masquerade = true
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.Info("cmd.config: Masquerade enabled")
+
+	return
+}
+
+func MasqueradeOff() (err error) {
+	config.Config.Masquerade = false
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.Info("cmd.config: Masquerade disabled")
+
+	return
+}
+
+func MasqueradeReverseOn() (err error) {
+	config.Config.MasqueradeReverse = true
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.Info("cmd.config: Reverse masquerade enabled")
+
+	return
+}
+
+func MasqueradeReverseOff() (err error) {
+	config.Config.MasqueradeReverse = false
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.Info("cmd.config: Reverse masquerade disabled")
+
+	return
+}
+
+func MasqueradeInterface(iface string) (err error) {
+	config.Config.MasqueradeInterface = iface
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"masquerade_interface": config.Config.MasqueradeInterface,
+	}).Info("cmd.config: Masquerade interface set")
+
+	return
+}
+
+func AddMasqueradeNetwork(network string) (err error) {
+	for _, masqNetwork := range config.Config.MasqueradeNetworks {
+		if masqNetwork == network {
+			return
+		}
+	}
+
+	config.Config.MasqueradeNetworks = append(
+		config.Config.MasqueradeNetworks, network)
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"masquerade_networks": config.Config.MasqueradeNetworks,
+	}).Info("cmd.config: Added masquerade network")
+
+	return
+}
+
+func RemoveMasqueradeNetwork(network string) (err error) {
+	masqNetworks := []string{}
+	for _, masqNetwork := range config.Config.MasqueradeNetworks {
+		if masqNetwork != network {
+			masqNetworks = append(masqNetworks, masqNetwork)
+		}
+	}
+	config.Config.MasqueradeNetworks = masqNetworks
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"masquerade_networks": config.Config.MasqueradeNetworks,
+	}).Info("cmd.config: Removed masquerade network")
+
+	return
+}
+
+func FirewallBackend(backend string) (err error) {
+	switch backend {
+	case "", "iptables", "nftables":
+		break
+	default:
+		err = &errortypes.ParseError{
+			errors.Newf("cmd.config: Unknown firewall backend '%s'", backend),
+		}
+		return
+	}
+
+	config.Config.FirewallBackend = backend
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"firewall_backend": config.Config.FirewallBackend,
+	}).Info("cmd.config: Firewall backend set")
+
+	return
+}
+
+func RouteBasedOn() (err error) {
+	config.Config.RouteBased = true
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.Info("cmd.config: Route based IPsec enabled")
+
+	return
+}
+
+func RouteBasedOff() (err error) {
+	config.Config.RouteBased = false
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.Info("cmd.config: Route based IPsec disabled")
+
+	return
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"strconv"
+
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/sirupsen/logrus"
+)
+
+func FrrNextHop(nextHop string) (err error) {
+	config.Config.Frr.NextHop = nextHop
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"next_hop": config.Config.Frr.NextHop,
+	}).Info("cmd.frr: Set FRR next hop")
+
+	return
+}
+
+func FrrNextHop6(nextHop6 string) (err error) {
+	config.Config.Frr.NextHop6 = nextHop6
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"next_hop6": config.Config.Frr.NextHop6,
+	}).Info("cmd.frr: Set FRR IPv6 next hop")
+
+	return
+}
+
+func FrrVrf(vrf string) (err error) {
+	config.Config.Frr.Vrf = vrf
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"vrf": config.Config.Frr.Vrf,
+	}).Info("cmd.frr: Set FRR vrf")
+
+	return
+}
+
+func FrrTag(tagStr string) (err error) {
+	tag, err := strconv.Atoi(tagStr)
+	if err != nil {
+		return
+	}
+
+	config.Config.Frr.Tag = tag
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"tag": config.Config.Frr.Tag,
+	}).Info("cmd.frr: Set FRR route tag")
+
+	return
+}
+
+func FrrDistance(distanceStr string) (err error) {
+	distance, err := strconv.Atoi(distanceStr)
+	if err != nil {
+		return
+	}
+
+	config.Config.Frr.Distance = distance
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"distance": config.Config.Frr.Distance,
+	}).Info("cmd.frr: Set FRR administrative distance")
+
+	return
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/sirupsen/logrus"
+)
+
+func IbmApiKey(apiKey string) (err error) {
+	config.Config.Ibm.ApiKey = apiKey
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"api_key": config.Config.Ibm.ApiKey,
+	}).Info("cmd.ibm: Set IBM Cloud API key")
+
+	return
+}
+
+func IbmRegion(region string) (err error) {
+	config.Config.Ibm.Region = region
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"region": config.Config.Ibm.Region,
+	}).Info("cmd.ibm: Set IBM Cloud region")
+
+	return
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"strconv"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/errortypes"
+	"github.com/sirupsen/logrus"
+)
+
+func MtuDiscoveryOn() (err error) {
+	config.Config.MtuDiscovery = true
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.Info("cmd.mtu: MTU discovery enabled")
+
+	return
+}
+
+func MtuDiscoveryOff() (err error) {
+	config.Config.MtuDiscovery = false
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.Info("cmd.mtu: MTU discovery disabled")
+
+	return
+}
+
+func MtuSet(stateId, linkId, mtuStr string) (err error) {
+	mtu, err := strconv.Atoi(mtuStr)
+	if err != nil || mtu < 576 || mtu > 9000 {
+		err = &errortypes.ParseError{
+			errors.Newf("cmd.mtu: Invalid mtu '%s'", mtuStr),
+		}
+		return
+	}
+
+	mtus := []*config.MtuData{}
+	for _, override := range config.Config.Mtus {
+		if override.State != stateId || override.Link != linkId {
+			mtus = append(mtus, override)
+		}
+	}
+	mtus = append(mtus, &config.MtuData{
+		State: stateId,
+		Link:  linkId,
+		Mtu:   mtu,
+	})
+	config.Config.Mtus = mtus
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"state": stateId,
+		"link":  linkId,
+		"mtu":   mtu,
+	}).Info("cmd.mtu: Set link mtu")
+
+	return
+}
+
+func MtuRemove(stateId, linkId string) (err error) {
+	mtus := []*config.MtuData{}
+	for _, override := range config.Config.Mtus {
+		if override.State != stateId || override.Link != linkId {
+			mtus = append(mtus, override)
+		}
+	}
+	config.Config.Mtus = mtus
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"state": stateId,
+		"link":  linkId,
+	}).Info("cmd.mtu: Removed link mtu")
+
+	return
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/sirupsen/logrus"
+)
+
+func OpenstackAuthUrl(authUrl string) (err error) {
+	config.Config.Openstack.AuthUrl = authUrl
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"auth_url": config.Config.Openstack.AuthUrl,
+	}).Info("cmd.openstack: Set OpenStack auth URL")
+
+	return
+}
+
+func OpenstackRegion(region string) (err error) {
+	config.Config.Openstack.Region = region
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"region": config.Config.Openstack.Region,
+	}).Info("cmd.openstack: Set OpenStack region")
+
+	return
+}
+
+func OpenstackDomain(domain string) (err error) {
+	config.Config.Openstack.Domain = domain
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"domain": config.Config.Openstack.Domain,
+	}).Info("cmd.openstack: Set OpenStack user domain")
+
+	return
+}
+
+func OpenstackProjectId(projectId string) (err error) {
+	config.Config.Openstack.ProjectId = projectId
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"project_id": config.Config.Openstack.ProjectId,
+	}).Info("cmd.openstack: Set OpenStack project id")
+
+	return
+}
+
+func OpenstackUsername(username string) (err error) {
+	config.Config.Openstack.Username = username
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"username": config.Config.Openstack.Username,
+	}).Info("cmd.openstack: Set OpenStack username")
+
+	return
+}
+
+func OpenstackPassword(password string) (err error) {
+	config.Config.Openstack.Password = password
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"password": config.Config.Openstack.Password,
+	}).Info("cmd.openstack: Set OpenStack password")
+
+	return
+}
+
+func OpenstackCredentialId(credentialId string) (err error) {
+	config.Config.Openstack.CredentialId = credentialId
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"credential_id": config.Config.Openstack.CredentialId,
+	}).Info("cmd.openstack: Set OpenStack application credential id")
+
+	return
+}
+
+func OpenstackCredentialSecret(credentialSecret string) (err error) {
+	config.Config.Openstack.CredentialSecret = credentialSecret
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"credential_secret": config.Config.Openstack.CredentialSecret,
+	}).Info("cmd.openstack: Set OpenStack application credential secret")
+
+	return
+}
+
+func OpenstackPortId(portId string) (err error) {
+	config.Config.Openstack.PortId = portId
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"port_id": config.Config.Openstack.PortId,
+	}).Info("cmd.openstack: Set OpenStack port id")
+
+	return
+}
+
+func OpenstackRouterId(routerId string) (err error) {
+	config.Config.Openstack.RouterId = routerId
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"router_id": config.Config.Openstack.RouterId,
+	}).Info("cmd.openstack: Set OpenStack router id")
+
+	return
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"strconv"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/errortypes"
+	"github.com/sirupsen/logrus"
+)
+
+func parseRate(rateStr string) (rate int, err error) {
+	rate, err = strconv.Atoi(rateStr)
+	if err != nil || rate < 0 {
+		err = &errortypes.ParseError{
+			errors.Newf("cmd.shape: Invalid rate '%s'", rateStr),
+		}
+		return
+	}
+
+	return
+}
+
+func getShape(stateId string) (shape *config.ShapeData) {
+	for _, shp := range config.Config.Shapes {
+		if shp.State == stateId {
+			shape = shp
+			return
+		}
+	}
+
+	shape = &config.ShapeData{
+		State: stateId,
+	}
+	config.Config.Shapes = append(config.Config.Shapes, shape)
+
+	return
+}
+
+func ShapeUplink(rateStr string) (err error) {
+	rate, err := parseRate(rateStr)
+	if err != nil {
+		return
+	}
+
+	config.Config.ShapeUplink = rate
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"shape_uplink": config.Config.ShapeUplink,
+	}).Info("cmd.shape: Set shaping uplink rate")
+
+	return
+}
+
+func ShapeEgress(stateId, rateStr string) (err error) {
+	rate, err := parseRate(rateStr)
+	if err != nil {
+		return
+	}
+
+	shape := getShape(stateId)
+	shape.Egress = rate
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"state":  shape.State,
+		"egress": shape.Egress,
+	}).Info("cmd.shape: Set link egress rate")
+
+	return
+}
+
+func ShapeIngress(stateId, rateStr string) (err error) {
+	rate, err := parseRate(rateStr)
+	if err != nil {
+		return
+	}
+
+	shape := getShape(stateId)
+	shape.Ingress = rate
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"state":   shape.State,
+		"ingress": shape.Ingress,
+	}).Info("cmd.shape: Set link ingress rate")
+
+	return
+}
+
+func ShapePriority(stateId, priorityStr string) (err error) {
+	priority, err := strconv.Atoi(priorityStr)
+	if err != nil || priority < 0 || priority > 7 {
+		err = &errortypes.ParseError{
+			errors.Newf("cmd.shape: Invalid priority '%s'", priorityStr),
+		}
+		return
+	}
+
+	shape := getShape(stateId)
+	shape.Priority = priority
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"state":    shape.State,
+		"priority": shape.Priority,
+	}).Info("cmd.shape: Set link priority")
+
+	return
+}
+
+func ShapeRemove(stateId string) (err error) {
+	shapes := []*config.ShapeData{}
+	for _, shape := range config.Config.Shapes {
+		if shape.State != stateId {
+			shapes = append(shapes, shape)
+		}
+	}
+	config.Config.Shapes = shapes
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"state": stateId,
+	}).Info("cmd.shape: Removed link shaping")
+
+	return
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/sirupsen/logrus"
+)
+
+func SshUsername(username string) (err error) {
+	config.Config.Ssh.Username = username
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"username": config.Config.Ssh.Username,
+	}).Info("cmd.ssh: Set SSH username")
+
+	return
+}
+
+func SshPrivateKey(privateKey string) (err error) {
+	config.Config.Ssh.PrivateKey = privateKey
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"private_key": config.Config.Ssh.PrivateKey,
+	}).Info("cmd.ssh: Set SSH private key path")
+
+	return
+}
+
+func SshKnownHosts(knownHosts string) (err error) {
+	config.Config.Ssh.KnownHosts = knownHosts
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"known_hosts": config.Config.Ssh.KnownHosts,
+	}).Info("cmd.ssh: Set SSH known hosts path")
+
+	return
+}
+
+func SshAddCommand(addCommand string) (err error) {
+	config.Config.Ssh.AddCommand = addCommand
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"add_command": config.Config.Ssh.AddCommand,
+	}).Info("cmd.ssh: Set SSH add route command")
+
+	return
+}
+
+func SshDeleteCommand(deleteCommand string) (err error) {
+	config.Config.Ssh.DeleteCommand = deleteCommand
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"delete_command": config.Config.Ssh.DeleteCommand,
+	}).Info("cmd.ssh: Set SSH delete route command")
+
+	return
+}
+
+func SshAddHost(host string) (err error) {
+	for _, hst := range config.Config.Ssh.Hosts {
+		if hst == host {
+			return
+		}
+	}
+
+	config.Config.Ssh.Hosts = append(config.Config.Ssh.Hosts, host)
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"hosts": config.Config.Ssh.Hosts,
+	}).Info("cmd.ssh: Added SSH host")
+
+	return
+}
+
+func SshRemoveHost(host string) (err error) {
+	hosts := []string{}
+	for _, hst := range config.Config.Ssh.Hosts {
+		if hst != host {
+			hosts = append(hosts, hst)
+		}
+	}
+	config.Config.Ssh.Hosts = hosts
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"hosts": config.Config.Ssh.Hosts,
+	}).Info("cmd.ssh: Removed SSH host")
+
+	return
+}
@@ -6,6 +6,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/pritunl/pritunl-link/bgp"
 	"github.com/pritunl/pritunl-link/clean"
 	"github.com/pritunl/pritunl-link/constants"
 	"github.com/pritunl/pritunl-link/interlink"
@@ -21,6 +22,7 @@ func Start() (err error) {
 
 	sync.Init()
 	watch.Init()
+	bgp.Init()
 
 	err = interlink.Init()
 	if err != nil {
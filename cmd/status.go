@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pritunl/pritunl-link/status"
+)
+
+func Status() (err error) {
+	stats, err := status.Get()
+	if err != nil {
+		return
+	}
+
+	saInfos, err := status.GetSaInfo()
+	if err != nil {
+		return
+	}
+
+	connIds := []string{}
+	for connId := range stats {
+		connIds = append(connIds, connId)
+	}
+	sort.Strings(connIds)
+
+	for _, connId := range connIds {
+		fmt.Printf("%s: %s\n", connId, stats[connId])
+
+		saInfo := saInfos[connId]
+		if saInfo == nil {
+			continue
+		}
+
+		fmt.Printf("  ike: %s\n", saInfo.IkeProposal)
+		fmt.Printf("  esp: %s\n", saInfo.EspProposal)
+		fmt.Printf("  dh_group: %s\n", saInfo.DhGroup)
+		fmt.Printf("  ike_rekey: %ds\n", saInfo.IkeRekey)
+		fmt.Printf("  esp_rekey: %ds\n", saInfo.EspRekey)
+		fmt.Printf("  nat_t: %t\n", saInfo.NatT)
+		fmt.Printf("  mobike: %t\n", saInfo.Mobike)
+	}
+
+	wgModes, err := status.GetWgModes()
+	if err != nil {
+		return
+	}
+
+	wgIfaces := []string{}
+	for iface := range wgModes {
+		wgIfaces = append(wgIfaces, iface)
+	}
+	sort.Strings(wgIfaces)
+
+	for _, iface := range wgIfaces {
+		fmt.Printf("%s: %s\n", iface, wgModes[iface])
+	}
+
+	return
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/errortypes"
+	"github.com/pritunl/pritunl-link/ipsec"
+	"github.com/sirupsen/logrus"
+)
+
+func getTuning(stateId, linkId string) (tuning *config.TuningData) {
+	for _, tun := range config.Config.Tunings {
+		if tun.State == stateId && tun.Link == linkId {
+			tuning = tun
+			return
+		}
+	}
+
+	tuning = &config.TuningData{
+		State: stateId,
+		Link:  linkId,
+	}
+	config.Config.Tunings = append(config.Config.Tunings, tuning)
+
+	return
+}
+
+func TuningSet(stateId, linkId, key, value string) (err error) {
+	if !ipsec.ValidTuning(key, value) {
+		err = &errortypes.ParseError{
+			errors.Newf("cmd.tuning: Invalid tuning option '%s=%s'",
+				key, value),
+		}
+		return
+	}
+
+	tuning := getTuning(stateId, linkId)
+
+	switch key {
+	case "ike_lifetime":
+		tuning.IkeLifetime = value
+	case "key_life":
+		tuning.KeyLife = value
+	case "rekey_margin":
+		tuning.RekeyMargin = value
+	case "dpd_delay":
+		tuning.DpdDelay = value
+	case "dpd_timeout":
+		tuning.DpdTimeout = value
+	case "mobike":
+		tuning.Mobike = value
+	case "auto":
+		tuning.Auto = value
+	}
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"state": stateId,
+		"link":  linkId,
+		"key":   key,
+		"value": value,
+	}).Info("cmd.tuning: Set link tuning option")
+
+	return
+}
+
+func TuningRemove(stateId, linkId string) (err error) {
+	tunings := []*config.TuningData{}
+	for _, tuning := range config.Config.Tunings {
+		if tuning.State != stateId || tuning.Link != linkId {
+			tunings = append(tunings, tuning)
+		}
+	}
+	config.Config.Tunings = tunings
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"state": stateId,
+		"link":  linkId,
+	}).Info("cmd.tuning: Removed link tuning options")
+
+	return
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/sirupsen/logrus"
+)
+
+func VyosHostname(hostname string) (err error) {
+	config.Config.Vyos.Hostname = hostname
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"hostname": config.Config.Vyos.Hostname,
+	}).Info("cmd.vyos: Set VyOS hostname")
+
+	return
+}
+
+func VyosKey(key string) (err error) {
+	config.Config.Vyos.Key = key
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"key": config.Config.Vyos.Key,
+	}).Info("cmd.vyos: Set VyOS API key")
+
+	return
+}
+
+func VyosInterface(iface string) (err error) {
+	config.Config.Vyos.Interface = iface
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"interface": config.Config.Vyos.Interface,
+	}).Info("cmd.vyos: Set VyOS inbound interface")
+
+	return
+}
+
+func VyosPortOn() (err error) {
+	config.Config.Vyos.DisablePort = false
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.Info("cmd.vyos: VyOS port forwarding enabled")
+
+	return
+}
+
+func VyosPortOff() (err error) {
+	config.Config.Vyos.DisablePort = true
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.Info("cmd.vyos: VyOS port forwarding disabled")
+
+	return
+}
@@ -0,0 +1,279 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/errortypes"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/term"
+)
+
+const passphraseEnv = "PRITUNL_LINK_PASSPHRASE"
+
+func getWgTuning(stateId string) (wgData *config.WgData) {
+	for _, data := range config.Config.WgTunings {
+		if data.State == stateId {
+			wgData = data
+			return
+		}
+	}
+
+	wgData = &config.WgData{
+		State: stateId,
+	}
+	config.Config.WgTunings = append(config.Config.WgTunings, wgData)
+
+	return
+}
+
+func WgTuningSet(stateId, key, value string) (err error) {
+	invalid := false
+	num, numErr := strconv.Atoi(value)
+
+	switch key {
+	case "mtu":
+		invalid = numErr != nil || (num != 0 && (num < 1280 || num > 9000))
+	case "keepalive":
+		if value == "off" {
+			num = -1
+		} else {
+			invalid = numErr != nil || num < 0 || num > 65535
+		}
+	case "fwmark", "handshake_timeout":
+		invalid = numErr != nil || num < 0
+	case "table":
+		if value == "auto" {
+			value = ""
+		} else if value != "off" && value != "main" {
+			invalid = numErr != nil || num <= 0
+		}
+	default:
+		invalid = true
+	}
+
+	if invalid {
+		err = &errortypes.ParseError{
+			errors.Newf("cmd.wg: Invalid wg tuning option '%s=%s'",
+				key, value),
+		}
+		return
+	}
+
+	wgData := getWgTuning(stateId)
+
+	switch key {
+	case "mtu":
+		wgData.Mtu = num
+	case "keepalive":
+		wgData.Keepalive = num
+	case "fwmark":
+		wgData.FwMark = num
+	case "table":
+		wgData.Table = value
+	case "handshake_timeout":
+		wgData.HandshakeTimeout = num
+	}
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"state": stateId,
+		"key":   key,
+		"value": value,
+	}).Info("cmd.wg: Set wg tuning option")
+
+	return
+}
+
+func WgTuningRemove(stateId string) (err error) {
+	wgTunings := []*config.WgData{}
+	for _, data := range config.Config.WgTunings {
+		if data.State != stateId {
+			wgTunings = append(wgTunings, data)
+		}
+	}
+	config.Config.WgTunings = wgTunings
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"state": stateId,
+	}).Info("cmd.wg: Removed wg tuning options")
+
+	return
+}
+
+func WgKeyRotate(stateId string) (err error) {
+	pubKey, err := config.State.RotateKey(stateId)
+	if err != nil {
+		return
+	}
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"state":      stateId,
+		"public_key": pubKey,
+	}).Info("cmd.wg: Rotated wg key, promoted after grace period")
+
+	return
+}
+
+func WgKeyRotation(daysStr string) (err error) {
+	days, err := strconv.Atoi(daysStr)
+	if err != nil || days < 0 {
+		err = &errortypes.ParseError{
+			errors.Newf("cmd.wg: Invalid rotation days '%s'", daysStr),
+		}
+		return
+	}
+
+	config.Config.WgKeyRotation = days
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"wg_key_rotation": config.Config.WgKeyRotation,
+	}).Info("cmd.wg: Set wg key rotation days")
+
+	return
+}
+
+func WgKeyGrace(graceStr string) (err error) {
+	grace, err := strconv.Atoi(graceStr)
+	if err != nil || grace < 0 {
+		err = &errortypes.ParseError{
+			errors.Newf("cmd.wg: Invalid grace period '%s'", graceStr),
+		}
+		return
+	}
+
+	config.Config.WgKeyGrace = grace
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"wg_key_grace": config.Config.WgKeyGrace,
+	}).Info("cmd.wg: Set wg key rotation grace period")
+
+	return
+}
+
+func getPassphrase() (passphrase string, err error) {
+	passphrase = os.Getenv(passphraseEnv)
+	if passphrase != "" {
+		return
+	}
+
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, "Passphrase: ")
+		data, e := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if e != nil {
+			err = &errortypes.ReadError{
+				errors.Wrap(e, "cmd.wg: Failed to read passphrase"),
+			}
+			return
+		}
+		passphrase = string(data)
+	} else {
+		line, e := bufio.NewReader(os.Stdin).ReadString('\n')
+		if e != nil && line == "" {
+			err = &errortypes.ReadError{
+				errors.Wrap(e, "cmd.wg: Failed to read passphrase"),
+			}
+			return
+		}
+		passphrase = strings.TrimRight(line, "\r\n")
+	}
+
+	if passphrase == "" {
+		err = &errortypes.ReadError{
+			errors.New("cmd.wg: Missing passphrase"),
+		}
+		return
+	}
+
+	return
+}
+
+func WgKeyExport(pth string) (err error) {
+	passphrase, err := getPassphrase()
+	if err != nil {
+		return
+	}
+
+	data, err := config.State.Export(passphrase)
+	if err != nil {
+		return
+	}
+
+	err = ioutil.WriteFile(pth, data, 0600)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "cmd.wg: Failed to write key export"),
+		}
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"path": pth,
+	}).Info("cmd.wg: Exported encrypted key store")
+
+	return
+}
+
+func WgKeyImport(pth string) (err error) {
+	passphrase, err := getPassphrase()
+	if err != nil {
+		return
+	}
+
+	data, err := ioutil.ReadFile(pth)
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "cmd.wg: Failed to read key export"),
+		}
+		return
+	}
+
+	count, err := config.State.Import(passphrase, data)
+	if err != nil {
+		return
+	}
+
+	err = config.Save()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"path":  pth,
+		"count": count,
+	}).Info("cmd.wg: Imported encrypted key store")
+
+	return
+}
@@ -26,6 +26,19 @@ type AwsData struct {
 	InterfaceId string `json:"interface_id"`
 }
 
+type AlibabaData struct {
+	Region          string `json:"region"`
+	VpcId           string `json:"vpc_id"`
+	InstanceId      string `json:"instance_id"`
+	AccessKeyId     string `json:"access_key_id"`
+	AccessKeySecret string `json:"access_key_secret"`
+}
+
+type IbmData struct {
+	ApiKey string `json:"api_key"`
+	Region string `json:"region"`
+}
+
 type GoogleData struct {
 	Project  string `json:"project"`
 	Network  string `json:"network"`
@@ -46,6 +59,19 @@ type OracleData struct {
 	VnicOcid        string `json:"vnic_ocid"`
 }
 
+type OpenstackData struct {
+	AuthUrl          string `json:"auth_url"`
+	Region           string `json:"region"`
+	Domain           string `json:"domain"`
+	ProjectId        string `json:"project_id"`
+	Username         string `json:"username"`
+	Password         string `json:"password"`
+	CredentialId     string `json:"credential_id"`
+	CredentialSecret string `json:"credential_secret"`
+	PortId           string `json:"port_id"`
+	RouterId         string `json:"router_id"`
+}
+
 type UnifiData struct {
 	DisablePort bool   `json:"disable_port"`
 	Controller  string `json:"controller"`
@@ -62,6 +88,103 @@ type EdgeData struct {
 	Password    string `json:"password"`
 }
 
+type VyosData struct {
+	DisablePort bool   `json:"disable_port"`
+	Hostname    string `json:"hostname"`
+	Key         string `json:"key"`
+	Interface   string `json:"interface"`
+}
+
+type BgpNeighbor struct {
+	Address     string   `json:"address"`
+	Asn         uint32   `json:"asn"`
+	Port        int      `json:"port"`
+	Med         uint32   `json:"med"`
+	LocalPref   uint32   `json:"local_pref"`
+	Communities []string `json:"communities"`
+}
+
+type BgpData struct {
+	Asn       uint32         `json:"asn"`
+	RouterId  string         `json:"router_id"`
+	HoldTime  int            `json:"hold_time"`
+	NextHop   string         `json:"next_hop"`
+	NextHop6  string         `json:"next_hop6"`
+	Neighbors []*BgpNeighbor `json:"neighbors"`
+}
+
+type FrrData struct {
+	NextHop  string `json:"next_hop"`
+	NextHop6 string `json:"next_hop6"`
+	Vrf      string `json:"vrf"`
+	Tag      int    `json:"tag"`
+	Distance int    `json:"distance"`
+}
+
+type SshData struct {
+	Username      string   `json:"username"`
+	PrivateKey    string   `json:"private_key"`
+	KnownHosts    string   `json:"known_hosts"`
+	Hosts         []string `json:"hosts"`
+	AddCommand    string   `json:"add_command"`
+	DeleteCommand string   `json:"delete_command"`
+}
+
+type NetmapData struct {
+	Network string `json:"network"`
+	Mapped  string `json:"mapped"`
+}
+
+type AclData struct {
+	Id          string `json:"id"`
+	State       string `json:"state"`
+	Link        string `json:"link"`
+	Action      string `json:"action"`
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Protocol    string `json:"protocol"`
+	Port        string `json:"port"`
+}
+
+type ShapeData struct {
+	State    string `json:"state"`
+	Egress   int    `json:"egress"`
+	Ingress  int    `json:"ingress"`
+	Priority int    `json:"priority"`
+}
+
+type MtuData struct {
+	State string `json:"state"`
+	Link  string `json:"link"`
+	Mtu   int    `json:"mtu"`
+}
+
+type TuningData struct {
+	State       string `json:"state"`
+	Link        string `json:"link"`
+	IkeLifetime string `json:"ike_lifetime"`
+	KeyLife     string `json:"key_life"`
+	RekeyMargin string `json:"rekey_margin"`
+	DpdDelay    string `json:"dpd_delay"`
+	DpdTimeout  string `json:"dpd_timeout"`
+	Mobike      string `json:"mobike"`
+	Auto        string `json:"auto"`
+}
+
+type WgData struct {
+	State            string `json:"state"`
+	Mtu              int    `json:"mtu"`
+	Keepalive        int    `json:"keepalive"`
+	FwMark           int    `json:"fwmark"`
+	Table            string `json:"table"`
+	HandshakeTimeout int    `json:"handshake_timeout"`
+}
+
+type CipherData struct {
+	State   string `json:"state"`
+	Profile string `json:"profile"`
+}
+
 type PritunlData struct {
 	Hostname       string `json:"hostname"`
 	OrganizationId string `json:"organization_id"`
@@ -71,32 +194,59 @@ type PritunlData struct {
 }
 
 type ConfigData struct {
-	loaded                     bool        `json:"-"`
-	Provider                   string      `json:"provider"`
-	DefaultInterface           string      `json:"default_interface"`
-	DefaultGateway             string      `json:"default_gateway"`
-	PublicAddress              string      `json:"public_address"`
-	LocalAddress               string      `json:"local_address"`
-	DirectSubnet               string      `json:"direct_subnet"`
-	DirectMode                 string      `json:"direct_mode"`
-	DirectSsh                  bool        `json:"direct_ssh"`
-	Address6                   string      `json:"address6"`
-	Uris                       []string    `json:"uris"`
-	SkipVerify                 bool        `json:"skip_verify"`
-	SkipHostCheck              bool        `json:"skip_host_check"`
-	Firewall                   bool        `json:"firewall"`
-	DeleteRoutes               bool        `json:"delete_routes"`
-	DisconnectedTimeout        int         `json:"disconnected_timeout"`
-	DisableAdvertiseUpdate     bool        `json:"disable_advertise_update"`
-	DisableDisconnectedRestart bool        `json:"disable_disconnected_restart"`
-	CustomOptions              []string    `json:"custom_options"`
-	Aws                        AwsData     `json:"aws"`
-	Google                     GoogleData  `json:"google"`
-	Hetzner                    HetznerData `json:"hetzner"`
-	Oracle                     OracleData  `json:"oracle"`
-	Unifi                      UnifiData   `json:"unifi"`
-	Edge                       EdgeData    `json:"edge"`
-	Pritunl                    PritunlData `json:"pritunl"`
+	loaded                     bool          `json:"-"`
+	Provider                   string        `json:"provider"`
+	DefaultInterface           string        `json:"default_interface"`
+	DefaultGateway             string        `json:"default_gateway"`
+	PublicAddress              string        `json:"public_address"`
+	LocalAddress               string        `json:"local_address"`
+	DirectSubnet               string        `json:"direct_subnet"`
+	DirectMode                 string        `json:"direct_mode"`
+	DirectSsh                  bool          `json:"direct_ssh"`
+	Address6                   string        `json:"address6"`
+	Uris                       []string      `json:"uris"`
+	SkipVerify                 bool          `json:"skip_verify"`
+	SkipHostCheck              bool          `json:"skip_host_check"`
+	Firewall                   bool          `json:"firewall"`
+	FirewallBackend            string        `json:"firewall_backend"`
+	DeleteRoutes               bool          `json:"delete_routes"`
+	DisconnectedTimeout        int           `json:"disconnected_timeout"`
+	DisableAdvertiseUpdate     bool          `json:"disable_advertise_update"`
+	DisableDisconnectedRestart bool          `json:"disable_disconnected_restart"`
+	CustomOptions              []string      `json:"custom_options"`
+	ProxyInterface             string        `json:"proxy_interface"`
+	ProxyNetworks              []string      `json:"proxy_networks"`
+	Netmaps                    []*NetmapData `json:"netmaps"`
+	Masquerade                 bool          `json:"masquerade"`
+	MasqueradeReverse          bool          `json:"masquerade_reverse"`
+	MasqueradeInterface        string        `json:"masquerade_interface"`
+	MasqueradeNetworks         []string      `json:"masquerade_networks"`
+	Acls                       []*AclData    `json:"acls"`
+	ShapeUplink                int           `json:"shape_uplink"`
+	Shapes                     []*ShapeData  `json:"shapes"`
+	MtuDiscovery               bool          `json:"mtu_discovery"`
+	RouteBased                 bool          `json:"route_based"`
+	Mtus                       []*MtuData    `json:"mtus"`
+	CipherProfile              string        `json:"cipher_profile"`
+	CipherProfiles             []*CipherData `json:"cipher_profiles"`
+	Tunings                    []*TuningData `json:"tunings"`
+	WgTunings                  []*WgData     `json:"wg_tunings"`
+	WgKeyRotation              int           `json:"wg_key_rotation"`
+	WgKeyGrace                 int           `json:"wg_key_grace"`
+	Aws                        AwsData       `json:"aws"`
+	Alibaba                    AlibabaData   `json:"alibaba"`
+	Ibm                        IbmData       `json:"ibm"`
+	Google                     GoogleData    `json:"google"`
+	Hetzner                    HetznerData   `json:"hetzner"`
+	Oracle                     OracleData    `json:"oracle"`
+	Openstack                  OpenstackData `json:"openstack"`
+	Unifi                      UnifiData     `json:"unifi"`
+	Edge                       EdgeData      `json:"edge"`
+	Vyos                       VyosData      `json:"vyos"`
+	Frr                        FrrData       `json:"frr"`
+	Ssh                        SshData       `json:"ssh"`
+	Pritunl                    PritunlData   `json:"pritunl"`
+	Bgp                        BgpData       `json:"bgp"`
 }
 
 func (c *ConfigData) Save() (err error) {
@@ -220,6 +370,21 @@ func Save() (err error) {
 	return
 }
 
+func GetWgData(stateId string) (data *WgData) {
+	for _, wgData := range Config.WgTunings {
+		if wgData.State == stateId {
+			data = wgData
+			return
+		}
+	}
+
+	data = &WgData{
+		State: stateId,
+	}
+
+	return
+}
+
 func GetModTime() (mod time.Time, err error) {
 	stat, err := os.Stat(constants.ConfPath)
 	if err != nil {
@@ -3,7 +3,11 @@ package config
 import (
 	"encoding/json"
 	"io/ioutil"
+	"net"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/dropbox/godropbox/errors"
 	"github.com/pritunl/pritunl-link/constants"
@@ -14,8 +18,17 @@ import (
 var State = &StateData{}
 
 type Link struct {
-	WgPublicKey  string `json:"wg_public_key"`
-	WgPrivateKey string `json:"wg_private_key"`
+	WgPublicKey      string   `json:"wg_public_key"`
+	WgPrivateKey     string   `json:"wg_private_key"`
+	WgTimestamp      int64    `json:"wg_timestamp,omitempty"`
+	WgNextPublicKey  string   `json:"wg_next_public_key,omitempty"`
+	WgNextPrivateKey string   `json:"wg_next_private_key,omitempty"`
+	WgNextTimestamp  int64    `json:"wg_next_timestamp,omitempty"`
+	WgNextReported   bool     `json:"wg_next_reported,omitempty"`
+	IpsecKey         string   `json:"ipsec_key,omitempty"`
+	IpsecCsr         string   `json:"ipsec_csr,omitempty"`
+	IpsecCert        string   `json:"ipsec_cert,omitempty"`
+	IpsecCaCerts     []string `json:"ipsec_ca_certs,omitempty"`
 }
 
 type StateData struct {
@@ -40,10 +53,11 @@ func (s *StateData) GenerateKey(linkId string) (
 	if s.Links == nil {
 		s.Links = map[string]Link{}
 	}
-	s.Links[linkId] = Link{
-		WgPublicKey:  pubKey,
-		WgPrivateKey: privKey,
-	}
+	link := s.Links[linkId]
+	link.WgPublicKey = pubKey
+	link.WgPrivateKey = privKey
+	link.WgTimestamp = time.Now().Unix()
+	s.Links[linkId] = link
 	s.lock.Unlock()
 
 	err = s.Save()
@@ -84,6 +98,283 @@ func (s *StateData) GetPrivateKey(linkId string) (privKey string, err error) {
 	return
 }
 
+func (s *StateData) RotateKey(linkId string) (pubKey string, err error) {
+	privateKey, err := utils.GeneratePrivateKey()
+	if err != nil {
+		return
+	}
+	pubKey = privateKey.PublicKey().String()
+
+	s.lock.Lock()
+	if s.Links == nil {
+		s.Links = map[string]Link{}
+	}
+	link := s.Links[linkId]
+	link.WgNextPublicKey = pubKey
+	link.WgNextPrivateKey = privateKey.String()
+	link.WgNextTimestamp = time.Now().Unix()
+	link.WgNextReported = false
+	s.Links[linkId] = link
+	s.lock.Unlock()
+
+	err = s.Save()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (s *StateData) GetNextPublicKey(linkId string) (pubKey string) {
+	s.lock.Lock()
+	pubKey = s.Links[linkId].WgNextPublicKey
+	s.lock.Unlock()
+	return
+}
+
+func (s *StateData) SetNextReported(linkId, pubKey string) (err error) {
+	s.lock.Lock()
+	link := s.Links[linkId]
+	if pubKey == "" || link.WgNextPublicKey != pubKey ||
+		link.WgNextReported {
+
+		s.lock.Unlock()
+		return
+	}
+	link.WgNextReported = true
+	link.WgNextTimestamp = time.Now().Unix()
+	s.Links[linkId] = link
+	s.lock.Unlock()
+
+	err = s.Save()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (s *StateData) GetKeyAge(linkId string) (age time.Duration,
+	err error) {
+
+	s.lock.Lock()
+	link := s.Links[linkId]
+	s.lock.Unlock()
+
+	if link.WgTimestamp == 0 {
+		if link.WgPrivateKey == "" {
+			return
+		}
+
+		s.lock.Lock()
+		link = s.Links[linkId]
+		link.WgTimestamp = time.Now().Unix()
+		s.Links[linkId] = link
+		s.lock.Unlock()
+
+		err = s.Save()
+		if err != nil {
+			return
+		}
+	}
+
+	age = time.Since(time.Unix(link.WgTimestamp, 0))
+
+	return
+}
+
+func (s *StateData) PromoteKey(linkId string, grace time.Duration) (
+	promoted bool, err error) {
+
+	s.lock.Lock()
+	link := s.Links[linkId]
+	if link.WgNextPrivateKey == "" || !link.WgNextReported ||
+		time.Since(time.Unix(link.WgNextTimestamp, 0)) < grace {
+
+		s.lock.Unlock()
+		return
+	}
+
+	link.WgPublicKey = link.WgNextPublicKey
+	link.WgPrivateKey = link.WgNextPrivateKey
+	link.WgTimestamp = time.Now().Unix()
+	link.WgNextPublicKey = ""
+	link.WgNextPrivateKey = ""
+	link.WgNextTimestamp = 0
+	link.WgNextReported = false
+	s.Links[linkId] = link
+	s.lock.Unlock()
+
+	err = s.Save()
+	if err != nil {
+		return
+	}
+	promoted = true
+
+	return
+}
+
+func (s *StateData) Export(passphrase string) (data []byte, err error) {
+	s.lock.Lock()
+	linksData, err := json.Marshal(s.Links)
+	s.lock.Unlock()
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "config: Failed to marshal key store"),
+		}
+		return
+	}
+
+	data, err = utils.Seal(passphrase, linksData)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (s *StateData) Import(passphrase string, data []byte) (
+	count int, err error) {
+
+	linksData, err := utils.Unseal(passphrase, data)
+	if err != nil {
+		return
+	}
+
+	links := map[string]Link{}
+	err = json.Unmarshal(linksData, &links)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "config: Failed to unmarshal key store"),
+		}
+		return
+	}
+
+	s.lock.Lock()
+	if s.Links == nil {
+		s.Links = map[string]Link{}
+	}
+	for linkId, link := range links {
+		s.Links[linkId] = link
+		count += 1
+	}
+	s.lock.Unlock()
+
+	err = s.Save()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (s *StateData) GetIpsecCsr(linkId string, addrs []string) (
+	csr string, err error) {
+
+	sortedAddrs := []string{}
+	for _, addr := range addrs {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			continue
+		}
+		sortedAddrs = append(sortedAddrs, ip.String())
+	}
+	sort.Strings(sortedAddrs)
+
+	s.lock.Lock()
+	if s.Links == nil {
+		s.Links = map[string]Link{}
+	}
+	link := s.Links[linkId]
+	s.lock.Unlock()
+
+	if link.IpsecKey != "" && link.IpsecCsr != "" &&
+		strings.Join(utils.GetCsrAddresses(link.IpsecCsr), ",") ==
+			strings.Join(sortedAddrs, ",") {
+
+		csr = link.IpsecCsr
+		return
+	}
+
+	key := link.IpsecKey
+	if key == "" {
+		key, err = utils.GenerateCertKey()
+		if err != nil {
+			return
+		}
+	}
+
+	csr, err = utils.GenerateCsr(key, linkId, sortedAddrs)
+	if err != nil {
+		return
+	}
+
+	s.lock.Lock()
+	link = s.Links[linkId]
+	if link.IpsecKey != key {
+		link.IpsecCert = ""
+	}
+	link.IpsecKey = key
+	link.IpsecCsr = csr
+	s.Links[linkId] = link
+	s.lock.Unlock()
+
+	err = s.Save()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (s *StateData) SetIpsecCert(linkId, cert string, caCerts []string) (
+	err error) {
+
+	s.lock.Lock()
+	link := s.Links[linkId]
+	if link.IpsecKey == "" || (link.IpsecCert == cert &&
+		strings.Join(link.IpsecCaCerts, "") == strings.Join(caCerts, "")) {
+
+		s.lock.Unlock()
+		return
+	}
+	link.IpsecCert = cert
+	link.IpsecCaCerts = caCerts
+	s.Links[linkId] = link
+	s.lock.Unlock()
+
+	err = s.Save()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (s *StateData) GetIpsecCert(linkId string) (
+	key, cert string, caCerts []string) {
+
+	s.lock.Lock()
+	link := s.Links[linkId]
+	s.lock.Unlock()
+
+	if link.IpsecKey == "" || link.IpsecCert == "" ||
+		len(link.IpsecCaCerts) == 0 {
+
+		return
+	}
+
+	if !utils.CheckCert(link.IpsecKey, link.IpsecCert) {
+		return
+	}
+
+	key = link.IpsecKey
+	cert = link.IpsecCert
+	caCerts = link.IpsecCaCerts
+
+	return
+}
+
 func (s *StateData) Save() (err error) {
 	saveLock.Lock()
 	defer saveLock.Unlock()
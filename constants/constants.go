@@ -13,6 +13,9 @@ const (
 	IpsecConfPath             = "/etc/ipsec.conf"
 	IpsecSecretsPath          = "/etc/ipsec.secrets"
 	IpsecDirPath              = "/etc/ipsec.pritunl"
+	IpsecCertsPath            = "/etc/ipsec.d/certs"
+	IpsecPrivatePath          = "/etc/ipsec.d/private"
+	IpsecCaCertsPath          = "/etc/ipsec.d/cacerts"
 	WgDirPath                 = "/etc/wireguard"
 	PublicIpServer            = "https://app4.pritunl.com/ip"
 	PublicIp6Server           = "https://app6.pritunl.com/ip"
@@ -27,4 +30,5 @@ var (
 	RoutesPath    = path.Join(VarDir, "routes")
 	CurRoutesPath = path.Join(VarDir, "cur_routes")
 	StatePath     = path.Join(VarDir, "state.json")
+	ProxyPath     = path.Join(VarDir, "proxy.json")
 )
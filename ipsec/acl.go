@@ -0,0 +1,112 @@
+package ipsec
+
+import (
+	"net"
+
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/iptables"
+	"github.com/pritunl/pritunl-link/state"
+)
+
+func getAclSource(source, network string) string {
+	_, linkNet, err := net.ParseCIDR(network)
+	if err != nil {
+		return ""
+	}
+
+	if source == "" {
+		return linkNet.String()
+	}
+
+	_, srcNet, err := net.ParseCIDR(source)
+	if err != nil {
+		return ""
+	}
+
+	srcOnes, srcBits := srcNet.Mask.Size()
+	linkOnes, linkBits := linkNet.Mask.Size()
+	if srcBits != linkBits {
+		return ""
+	}
+
+	if srcOnes >= linkOnes && linkNet.Contains(srcNet.IP) {
+		return srcNet.String()
+	}
+	if linkOnes >= srcOnes && srcNet.Contains(linkNet.IP) {
+		return linkNet.String()
+	}
+
+	return ""
+}
+
+func isSameFamily(network, network2 string) bool {
+	ip, _, err := net.ParseCIDR(network)
+	if err != nil {
+		return false
+	}
+
+	ip2, _, err := net.ParseCIDR(network2)
+	if err != nil {
+		return false
+	}
+
+	return (ip.To4() == nil) == (ip2.To4() == nil)
+}
+
+func putAclIpTables(states []*state.State) (err error) {
+	if len(config.Config.Acls) == 0 {
+		return
+	}
+
+	rules := []*iptables.AclRule{}
+	for _, acl := range config.Config.Acls {
+		for _, stat := range states {
+			if stat.Type == state.DirectClient ||
+				stat.Type == state.DirectServer {
+
+				continue
+			}
+
+			if acl.State != "" && acl.State != stat.Id {
+				continue
+			}
+
+			for _, link := range stat.Links {
+				if acl.Link != "" && acl.Link != link.Id {
+					continue
+				}
+				iface := getLinkIface(stat, link)
+
+				for _, network := range link.RightSubnets {
+					source := getAclSource(acl.Source, network)
+					if source == "" {
+						continue
+					}
+
+					if acl.Destination != "" &&
+						!isSameFamily(source, acl.Destination) {
+
+						continue
+					}
+
+					rules = append(rules, &iptables.AclRule{
+						Id:          acl.Id,
+						Action:      acl.Action,
+						Interface:   iface,
+						Source:      source,
+						Destination: acl.Destination,
+						Protocol:    acl.Protocol,
+						Port:        acl.Port,
+					})
+				}
+			}
+		}
+	}
+
+	err = iptables.SetAcls(rules)
+	if err != nil {
+		return
+	}
+
+	return
+}
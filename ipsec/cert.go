@@ -0,0 +1,112 @@
+package ipsec
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/constants"
+	"github.com/pritunl/pritunl-link/errortypes"
+	"github.com/pritunl/pritunl-link/state"
+	"github.com/pritunl/pritunl-link/utils"
+)
+
+const certPrefix = "pritunl-"
+
+func getCertName(stateId string) string {
+	return fmt.Sprintf("%s%s.pem", certPrefix, stateId)
+}
+
+func writeCertFile(dir, name, data string, perm os.FileMode) (err error) {
+	err = utils.ExistsMkdir(dir, 0755)
+	if err != nil {
+		return
+	}
+
+	err = ioutil.WriteFile(path.Join(dir, name), []byte(data), perm)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "ipsec: Failed to write certificate"),
+		}
+		return
+	}
+
+	return
+}
+
+func cleanCertDir(dir string, names map[string]bool) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, file := range files {
+		name := file.Name()
+		if !strings.HasPrefix(name, certPrefix) || names[name] {
+			continue
+		}
+
+		os.Remove(path.Join(dir, name))
+	}
+}
+
+func writeCerts(states []*state.State) (certs map[string]string,
+	err error) {
+
+	certs = map[string]string{}
+	keyNames := map[string]bool{}
+	certNames := map[string]bool{}
+	caNames := map[string]bool{}
+
+	for _, stat := range states {
+		if stat.Protocol != "" && stat.Protocol != "ipsec" {
+			continue
+		}
+
+		if stat.IpsecCert == "" {
+			continue
+		}
+
+		key, cert, caCerts := config.State.GetIpsecCert(stat.Id)
+		if cert == "" {
+			continue
+		}
+
+		name := getCertName(stat.Id)
+
+		err = writeCertFile(constants.IpsecPrivatePath, name, key, 0600)
+		if err != nil {
+			return
+		}
+		keyNames[name] = true
+
+		err = writeCertFile(constants.IpsecCertsPath, name, cert, 0644)
+		if err != nil {
+			return
+		}
+		certNames[name] = true
+
+		for i, caCert := range caCerts {
+			caName := fmt.Sprintf("%s%s-%d.pem", certPrefix, stat.Id, i)
+
+			err = writeCertFile(constants.IpsecCaCertsPath,
+				caName, caCert, 0644)
+			if err != nil {
+				return
+			}
+			caNames[caName] = true
+		}
+
+		certs[stat.Id] = name
+	}
+
+	cleanCertDir(constants.IpsecPrivatePath, keyNames)
+	cleanCertDir(constants.IpsecCertsPath, certNames)
+	cleanCertDir(constants.IpsecCaCertsPath, caNames)
+
+	return
+}
@@ -0,0 +1,235 @@
+package ipsec
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/errortypes"
+	"github.com/pritunl/pritunl-link/state"
+	"github.com/pritunl/pritunl-link/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	CipherCompatible = "compatible"
+	CipherFips       = "fips"
+	CipherModern     = "modern"
+	CipherPq         = "pq"
+)
+
+var (
+	ipsecVersion     = 0
+	ipsecVersionLock = sync.Mutex{}
+	ipsecVersionReg  = regexp.MustCompile(`strongSwan U?([0-9]+)\.`)
+)
+
+type cipherProfile struct {
+	Ike        string
+	Esp        string
+	Strict     bool
+	MinVersion int
+}
+
+var cipherProfiles = map[string]*cipherProfile{
+	CipherCompatible: &cipherProfile{
+		Ike: ikeCiphers,
+		Esp: espCiphers,
+	},
+	CipherFips: &cipherProfile{
+		Ike:    "aes256gcm16-prfsha384-ecp384,aes128gcm16-prfsha256-ecp256,aes256-sha384-ecp384,aes128-sha256-ecp256,aes256-sha384-modp3072,aes128-sha256-modp3072",
+		Esp:    "aes256gcm16-ecp384,aes128gcm16-ecp256,aes256-sha384-ecp384,aes128-sha256-ecp256,aes256-sha384-modp3072,aes128-sha256-modp3072",
+		Strict: true,
+	},
+	CipherModern: &cipherProfile{
+		Ike:    "aes256gcm16-prfsha384-x25519,aes128gcm16-prfsha256-x25519,chacha20poly1305-prfsha256-x25519,aes256gcm16-prfsha384-ecp384",
+		Esp:    "aes256gcm16-x25519,aes128gcm16-x25519,chacha20poly1305-x25519,aes256gcm16-ecp384",
+		Strict: true,
+	},
+	CipherPq: &cipherProfile{
+		Ike:        "aes256gcm16-prfsha384-x25519-ke1_mlkem768,aes256gcm16-prfsha384-ecp384-ke1_mlkem768,aes128gcm16-prfsha256-x25519-ke1_mlkem768",
+		Esp:        "aes256gcm16-x25519-ke1_mlkem768,aes256gcm16-ecp384-ke1_mlkem768,aes128gcm16-x25519-ke1_mlkem768",
+		Strict:     true,
+		MinVersion: 6,
+	},
+}
+
+func ValidCipherProfile(name string) bool {
+	_, ok := cipherProfiles[name]
+	return ok
+}
+
+func getIpsecVersion() (version int, err error) {
+	ipsecVersionLock.Lock()
+	defer ipsecVersionLock.Unlock()
+
+	if ipsecVersion != 0 {
+		version = ipsecVersion
+		return
+	}
+
+	output, err := utils.ExecOutput("", "ipsec", "--version")
+	if err != nil {
+		return
+	}
+
+	match := ipsecVersionReg.FindStringSubmatch(output)
+	if match == nil {
+		err = &errortypes.ParseError{
+			errors.New("ipsec: Failed to parse strongSwan version"),
+		}
+		return
+	}
+
+	version, err = strconv.Atoi(match[1])
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "ipsec: Failed to parse strongSwan version"),
+		}
+		return
+	}
+
+	ipsecVersion = version
+
+	return
+}
+
+func CheckCipherProfile(name string) (err error) {
+	profile := cipherProfiles[name]
+	if profile == nil || profile.MinVersion == 0 {
+		return
+	}
+
+	version, err := getIpsecVersion()
+	if err != nil {
+		return
+	}
+
+	if version < profile.MinVersion {
+		err = &errortypes.UnknownError{
+			errors.Newf("ipsec: Cipher profile '%s' requires "+
+				"strongSwan %d with the stroke plugin, found strongSwan %d",
+				name, profile.MinVersion, version),
+		}
+		return
+	}
+
+	return
+}
+
+func getCipherProfile(stateId string) string {
+	for _, profile := range config.Config.CipherProfiles {
+		if profile.State == stateId {
+			return profile.Profile
+		}
+	}
+
+	return config.Config.CipherProfile
+}
+
+func inCipherProfile(ciphers, profile string) bool {
+	allowed := map[string]bool{}
+	for _, cipher := range strings.Split(profile, ",") {
+		allowed[cipher] = true
+	}
+
+	for _, cipher := range strings.Split(ciphers, ",") {
+		cipher = strings.TrimSuffix(
+			strings.ToLower(strings.TrimSpace(cipher)), "!")
+		if cipher == "" {
+			continue
+		}
+
+		if !allowed[cipher] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func getCipherList(preferred, ciphers string, force, strict bool) string {
+	list := ""
+	if preferred != "" {
+		if force {
+			list = preferred
+		} else {
+			list = preferred + "," + ciphers
+		}
+	} else {
+		list = ciphers
+	}
+
+	if strict && !strings.HasSuffix(list, "!") {
+		list += "!"
+	}
+
+	return list
+}
+
+func getCiphers(stat *state.State) (ike, esp string) {
+	profileName := getCipherProfile(stat.Id)
+	preferredIke := stat.PreferredIke
+	preferredEsp := stat.PreferredEsp
+
+	profile := cipherProfiles[profileName]
+	if profile == nil {
+		if profileName != "" {
+			logrus.WithFields(logrus.Fields{
+				"state_id": stat.Id,
+				"profile":  profileName,
+			}).Error("ipsec: Unknown cipher profile, using compatible")
+		}
+
+		ike = getCipherList(preferredIke, ikeCiphers,
+			stat.ForcePreferred, false)
+		esp = getCipherList(preferredEsp, espCiphers,
+			stat.ForcePreferred, false)
+		return
+	}
+
+	if profile.MinVersion != 0 {
+		e := CheckCipherProfile(profileName)
+		if e != nil {
+			logrus.WithFields(logrus.Fields{
+				"state_id": stat.Id,
+				"profile":  profileName,
+				"error":    e,
+			}).Error("ipsec: Unsupported cipher profile, using compatible")
+
+			ike = getCipherList(preferredIke, ikeCiphers,
+				stat.ForcePreferred, false)
+			esp = getCipherList(preferredEsp, espCiphers,
+				stat.ForcePreferred, false)
+			return
+		}
+	}
+
+	if preferredIke != "" && !inCipherProfile(preferredIke, profile.Ike) {
+		logrus.WithFields(logrus.Fields{
+			"state_id":      stat.Id,
+			"profile":       profileName,
+			"preferred_ike": preferredIke,
+		}).Warn("ipsec: Rejected preferred IKE ciphers outside profile")
+		preferredIke = ""
+	}
+
+	if preferredEsp != "" && !inCipherProfile(preferredEsp, profile.Esp) {
+		logrus.WithFields(logrus.Fields{
+			"state_id":      stat.Id,
+			"profile":       profileName,
+			"preferred_esp": preferredEsp,
+		}).Warn("ipsec: Rejected preferred ESP ciphers outside profile")
+		preferredEsp = ""
+	}
+
+	ike = getCipherList(preferredIke, profile.Ike,
+		stat.ForcePreferred, profile.Strict)
+	esp = getCipherList(preferredEsp, profile.Esp,
+		stat.ForcePreferred, profile.Strict)
+
+	return
+}
@@ -13,52 +13,49 @@ const (
 	defaultDirectNetwork = "10.197.197.196/30"
 	defaultDirectMode    = DirectGre
 	confTemplateStr      = `conn {{.Id}}
-	ikelifetime=8h
-	keylife=1h
-	rekeymargin=9m
+	ikelifetime={{.IkeLifetime}}
+	keylife={{.KeyLife}}
+	rekeymargin={{.RekeyMargin}}
 	keyingtries=%forever
+{{- if .Cert}}
+	leftauth=pubkey
+	rightauth=pubkey
+	leftcert={{.Cert}}
+{{- else}}
 	authby=secret
+{{- end}}
 	keyexchange=ikev2
 	ike={{.IkeCiphers}}
 	esp={{.EspCiphers}}
-	mobike=yes
-	dpddelay=5s
-	dpdtimeout=15s
+	mobike={{.Mobike}}
+	dpddelay={{.DpdDelay}}
+	dpdtimeout={{.DpdTimeout}}
 	dpdaction={{.Action}}
 	left=%defaultroute
 	leftid={{.Left}}
 	leftsubnet={{.LeftSubnets}}
 	right={{.Right}}
-	rightid={{.Right}}
+	rightid={{.RightId}}
 	rightsubnet={{.RightSubnets}}
-	auto=start
+{{- if .IfId}}
+	if_id_in={{.IfId}}
+	if_id_out={{.IfId}}
+{{- end}}
+	auto={{.Auto}}
 `
 	espCiphers         = "aes128gcm128-x25519,aes128-sha256-curve25519,aes128-sha256-modp2048s256,aes128-sha256-ecp256,aes128-sha256-modp3072,aes192-sha384-modp2048s256,aes192-sha384-ecp384,aes192-sha384-curve25519,aes256-sha512-modp2048s256,aes256-sha512-ecp521,aes256-sha512-curve25519,aes128-sha256-modp4096,aes128-sha256-modp2048,aes128-sha256-modp1536,aes128-sha1-modp2048s256,aes128-sha1-ecp256,aes128-sha1-modp3072,aes128-sha1-curve25519,aes128-sha1-modp4096,aes128-sha1-modp3072,aes128-sha1-modp2048,aes128-sha1-modp1536"
 	ikeCiphers         = "aes128-sha256-x25519,aes128-sha256-curve25519,aes128-sha256-modp2048s256,aes128-sha256-ecp256,aes128-sha256-modp3072,aes192-sha384-modp2048s256,aes192-sha384-ecp384,aes192-sha384-curve25519,aes256-sha512-modp2048s256,aes256-sha512-ecp521,aes256-sha512-curve25519,aes128-sha256-modp4096,aes128-sha256-modp2048,aes128-sha256-modp1536,aes128-sha1-modp2048s256,aes128-sha1-ecp256,aes128-sha1-modp3072,aes128-sha1-curve25519,aes128-sha1-modp4096,aes128-sha1-modp3072,aes128-sha1-modp2048,aes128-sha1-modp1536"
 	secretsTemplateStr = `{{.Left}} {{.Right}} : PSK "{{.PreSharedKey}}"
 `
-	confWgTemplateStr = `# pritunl-link:{{.WgHash}}
-[Interface]
-PrivateKey = {{.WgPrivateKey}}
-ListenPort = {{.WgPort}}
-`
-	confWgPeerTemplateStr = `
-[Peer]
-PublicKey = {{.WgPublicKey}}
-PresharedKey = {{.WgPreSharedKey}}
-AllowedIPs = {{.RightSubnets}}
-Endpoint = {{.RightWg}}:{{.WgPort}}
-PersistentKeepalive = 25
+	secretsCertTemplateStr = `: ECDSA {{.Cert}}
 `
 )
 
 var (
 	confTemplate = template.Must(
 		template.New("conf").Parse(confTemplateStr))
-	confWgTemplate = template.Must(
-		template.New("wg_conf").Parse(confWgTemplateStr))
-	confWgPeerTemplate = template.Must(
-		template.New("wg_peer").Parse(confWgPeerTemplateStr))
 	secretsTemplate = template.Must(
 		template.New("secrets").Parse(secretsTemplateStr))
+	secretsCertTemplate = template.Must(
+		template.New("secrets_cert").Parse(secretsCertTemplateStr))
 )
@@ -11,7 +11,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/dropbox/godropbox/container/set"
 	"github.com/dropbox/godropbox/errors"
 	"github.com/pritunl/pritunl-link/advertise"
 	"github.com/pritunl/pritunl-link/config"
@@ -32,26 +31,28 @@ var (
 	deployLock      sync.Mutex
 	updateSleepLock sync.Mutex
 	updateSleep     = constants.UpdateAdvertiseRate
-	wgHash          = ""
 )
 
 type templateData struct {
-	Id             string
-	Action         string
-	Left           string
-	LeftWg         string
-	LeftSubnets    string
-	Right          string
-	RightSubnets   string
-	RightWg        string
-	PreSharedKey   string
-	WgHash         string
-	WgPort         int
-	WgPreSharedKey string
-	WgPublicKey    string
-	WgPrivateKey   string
-	IkeCiphers     string
-	EspCiphers     string
+	Id           string
+	Action       string
+	Left         string
+	LeftSubnets  string
+	Right        string
+	RightId      string
+	RightSubnets string
+	PreSharedKey string
+	Cert         string
+	IfId         uint32
+	IkeLifetime  string
+	KeyLife      string
+	RekeyMargin  string
+	DpdDelay     string
+	DpdTimeout   string
+	Mobike       string
+	Auto         string
+	IkeCiphers   string
+	EspCiphers   string
 }
 
 func putIpTables(stat *state.State) (err error) {
@@ -233,6 +234,14 @@ func putIpTables(stat *state.State) (err error) {
 		directSource = clientLocal
 	}
 
+	directMss := "1320"
+	if mtu := getLinkMtu(stat, stat.Links[0]); mtu != 0 {
+		if directMode == DirectGre {
+			mtu -= greOverhead
+		}
+		directMss = strconv.Itoa(mtu - 40)
+	}
+
 	err = iptables.UpsertRule(
 		"nat",
 		"PREROUTING",
@@ -279,7 +288,7 @@ func putIpTables(stat *state.State) (err error) {
 		"-m", "tcp",
 		"--tcp-flags", "SYN,RST", "SYN",
 		"-j", "TCPMSS",
-		"--set-mss", "1320",
+		"--set-mss", directMss,
 		"-m", "comment",
 		"--comment", "pritunl-link-direct",
 	)
@@ -341,12 +350,32 @@ func writeTemplates(states []*state.State) (iptablesState bool, err error) {
 
 	confs := map[string]*bytes.Buffer{}
 
+	certs, err := writeCerts(states)
+	if err != nil {
+		return
+	}
+
 	for _, stat := range states {
 		if stat.Protocol != "" && stat.Protocol != "ipsec" {
 			continue
 		}
 
 		confBuf := &bytes.Buffer{}
+		cert := certs[stat.Id]
+		ikeCiphersData, espCiphersData := getCiphers(stat)
+
+		if cert != "" {
+			err = secretsCertTemplate.Execute(secretsBuf, &templateData{
+				Cert: cert,
+			})
+			if err != nil {
+				err = &errortypes.ParseError{
+					errors.Wrap(err,
+						"ipsec: Failed to execute secrets template"),
+				}
+				return
+			}
+		}
 
 		for _, link := range stat.Links {
 			leftSubnets := strings.Join(link.LeftSubnets, ",")
@@ -360,6 +389,14 @@ func writeTemplates(states []*state.State) (iptablesState bool, err error) {
 				}
 			}
 
+			routeBased := isRouteBased(stat)
+			ifId := uint32(0)
+			if routeBased {
+				leftSubnets = getXfrmSelectors(link.LeftSubnets)
+				rightSubnets = getXfrmSelectors(link.RightSubnets)
+				ifId = GetXfrmIfId(stat.Id, link.Id)
+			}
+
 			left := ""
 			if stat.Ipv6 {
 				left = publicAddr6
@@ -367,31 +404,16 @@ func writeTemplates(states []*state.State) (iptablesState bool, err error) {
 				left = publicAddr
 			}
 
-			action := "restart"
-			if stat.Action != "" {
-				action = stat.Action
-			}
+			tun := getTuning(stat, link)
 
-			ikeCiphersData := ""
-			if stat.PreferredIke != "" {
-				if stat.ForcePreferred {
-					ikeCiphersData = stat.PreferredIke
-				} else {
-					ikeCiphersData = stat.PreferredIke + "," + ikeCiphers
-				}
-			} else {
-				ikeCiphersData = ikeCiphers
+			rightId := link.Right
+			if cert != "" && link.RightId != "" {
+				rightId = link.RightId
 			}
 
-			espCiphersData := ""
-			if stat.PreferredEsp != "" {
-				if stat.ForcePreferred {
-					espCiphersData = stat.PreferredEsp
-				} else {
-					espCiphersData = stat.PreferredEsp + "," + espCiphers
-				}
-			} else {
-				espCiphersData = espCiphers
+			action := "restart"
+			if stat.Action != "" {
+				action = stat.Action
 			}
 
 			data := &templateData{
@@ -400,10 +422,20 @@ func writeTemplates(states []*state.State) (iptablesState bool, err error) {
 				Left:         left,
 				LeftSubnets:  leftSubnets,
 				Right:        link.Right,
+				RightId:      rightId,
 				RightSubnets: rightSubnets,
 				PreSharedKey: link.PreSharedKey,
+				Cert:         cert,
 				IkeCiphers:   ikeCiphersData,
 				EspCiphers:   espCiphersData,
+				IfId:         ifId,
+				IkeLifetime:  tun.IkeLifetime,
+				KeyLife:      tun.KeyLife,
+				RekeyMargin:  tun.RekeyMargin,
+				DpdDelay:     tun.DpdDelay,
+				DpdTimeout:   tun.DpdTimeout,
+				Mobike:       tun.Mobike,
+				Auto:         tun.Auto,
 			}
 
 			err = confTemplate.Execute(confBuf, data)
@@ -428,7 +460,7 @@ func writeTemplates(states []*state.State) (iptablesState bool, err error) {
 				}
 			}
 
-			if link.Static && (len(link.LeftSubnets) > 1 ||
+			if !routeBased && link.Static && (len(link.LeftSubnets) > 1 ||
 				len(link.RightSubnets) > 1) {
 
 				for x, leftSubnet := range link.LeftSubnets {
@@ -440,10 +472,19 @@ func writeTemplates(states []*state.State) (iptablesState bool, err error) {
 							Left:         left,
 							LeftSubnets:  leftSubnet,
 							Right:        link.Right,
+							RightId:      rightId,
 							RightSubnets: rightSubnet,
 							PreSharedKey: link.PreSharedKey,
+							Cert:         cert,
 							IkeCiphers:   ikeCiphersData,
 							EspCiphers:   espCiphersData,
+							IkeLifetime:  tun.IkeLifetime,
+							KeyLife:      tun.KeyLife,
+							RekeyMargin:  tun.RekeyMargin,
+							DpdDelay:     tun.DpdDelay,
+							DpdTimeout:   tun.DpdTimeout,
+							Mobike:       tun.Mobike,
+							Auto:         tun.Auto,
 						}
 
 						err = confTemplate.Execute(confBuf, data)
@@ -471,13 +512,15 @@ func writeTemplates(states []*state.State) (iptablesState bool, err error) {
 				}
 			}
 
-			err = secretsTemplate.Execute(secretsBuf, data)
-			if err != nil {
-				err = &errortypes.ParseError{
-					errors.Wrap(err,
-						"ipsec: Failed to execute secrets template"),
+			if cert == "" {
+				err = secretsTemplate.Execute(secretsBuf, data)
+				if err != nil {
+					err = &errortypes.ParseError{
+						errors.Wrap(err,
+							"ipsec: Failed to execute secrets template"),
+					}
+					return
 				}
-				return
 			}
 		}
 
@@ -524,86 +567,63 @@ func writeTemplates(states []*state.State) (iptablesState bool, err error) {
 	return
 }
 
-func writeWgTemplates(states []*state.State) (wgIfaces, modWgIfaces []string,
+func getWgConfs(states []*state.State) (wgConfs map[string]*wgConf,
 	iptablesState bool, err error) {
 
-	publicAddr := state.GetPublicAddress()
-	publicAddr6 := state.GetAddress6()
-
-	confs := map[string]*bytes.Buffer{}
+	wgConfs = map[string]*wgConf{}
 
 	for _, stat := range states {
 		if stat.Protocol != "wg" {
 			continue
 		}
 
-		confBuf := &bytes.Buffer{}
-
 		privKey, e := config.State.GetPrivateKey(stat.Id)
 		if e != nil {
 			err = e
 			return
 		}
 
-		data := &templateData{
-			WgHash:       wgHash,
-			WgPort:       stat.WgPort,
-			WgPrivateKey: privKey,
+		wgData := config.GetWgData(stat.Id)
+
+		keepalive := wgKeepalive
+		if wgData.Keepalive > 0 {
+			keepalive = wgData.Keepalive
+		} else if wgData.Keepalive < 0 {
+			keepalive = 0
 		}
 
-		err = confWgTemplate.Execute(confBuf, data)
-		if err != nil {
-			err = &errortypes.ParseError{
-				errors.Wrap(err,
-					"ipsec: Failed to execute conf template"),
-			}
-			return
+		iface := GetWgIface(stat.Id)
+		conf := &wgConf{
+			StateId:    stat.Id,
+			Iface:      iface,
+			PrivateKey: privKey,
+			Port:       stat.WgPort,
+			Mtu:        getStateMtu(stat),
+			FwMark:     wgData.FwMark,
+			Table:      wgData.Table,
 		}
 
 		for _, link := range stat.Links {
-			leftSubnets := strings.Join(link.LeftSubnets, ",")
 			rightSubnets := strings.Join(link.RightSubnets, ",")
 
 			if link.WgPublicKey == "" {
 				continue
 			}
 
-			if GetDirectMode() == DirectPolicy {
-				if stat.Type == state.DirectServer {
-					leftSubnets = "0.0.0.0/0"
-				} else if stat.Type == state.DirectClient {
-					rightSubnets = "0.0.0.0/0"
-				}
-			}
-
-			left := ""
-			if stat.Ipv6 {
-				left = publicAddr6
-			} else {
-				left = publicAddr
-			}
+			if GetDirectMode() == DirectPolicy &&
+				stat.Type == state.DirectClient {
 
-			data := &templateData{
-				Id:             state.GetLinkId(stat.Id, link.Id, link.Hash),
-				Left:           left,
-				LeftWg:         utils.FormatHost(left),
-				LeftSubnets:    leftSubnets,
-				Right:          link.Right,
-				RightWg:        utils.FormatHost(link.Right),
-				RightSubnets:   rightSubnets,
-				WgPort:         stat.WgPort,
-				WgPublicKey:    link.WgPublicKey,
-				WgPreSharedKey: PreSharedKeyToWg(link.PreSharedKey),
+				rightSubnets = "0.0.0.0/0"
 			}
 
-			err = confWgPeerTemplate.Execute(confBuf, data)
-			if err != nil {
-				err = &errortypes.ParseError{
-					errors.Wrap(err,
-						"ipsec: Failed to execute conf template"),
-				}
-				return
-			}
+			conf.Peers = append(conf.Peers, &wgPeer{
+				PublicKey:    link.WgPublicKey,
+				PreSharedKey: PreSharedKeyToWg(link.PreSharedKey),
+				Endpoint: fmt.Sprintf("%s:%d",
+					utils.FormatHost(link.Right), stat.WgPort),
+				AllowedIps: strings.Split(rightSubnets, ","),
+				Keepalive:  keepalive,
+			})
 		}
 
 		if stat.Type == state.DirectServer && len(stat.Links) != 0 {
@@ -615,27 +635,7 @@ func writeWgTemplates(states []*state.State) (wgIfaces, modWgIfaces []string,
 			}
 		}
 
-		iface := GetWgIface(stat.Id)
-		wgIfaces = append(wgIfaces, iface)
-		confs[iface] = confBuf
-	}
-
-	for iface, confBuf := range confs {
-		pth := path.Join(constants.WgDirPath, fmt.Sprintf("%s.conf", iface))
-
-		curData, _ := ioutil.ReadFile(pth)
-		newData := confBuf.Bytes()
-
-		if !bytes.Equal(curData, newData) {
-			modWgIfaces = append(modWgIfaces, iface)
-			err = ioutil.WriteFile(pth, confBuf.Bytes(), 0600)
-			if err != nil {
-				err = &errortypes.WriteError{
-					errors.Wrap(err, "ipsec: Failed to write state conf"),
-				}
-				return
-			}
-		}
+		wgConfs[iface] = conf
 	}
 
 	return
@@ -699,7 +699,7 @@ func deploy(states []*state.State, restart bool) (err error) {
 		return
 	}
 
-	wgIfaces, modWgIfaces, iptablesWgState, err := writeWgTemplates(states)
+	wgConfs, iptablesWgState, err := getWgConfs(states)
 	if err != nil {
 		return
 	}
@@ -711,61 +711,50 @@ func deploy(states []*state.State, restart bool) (err error) {
 		}
 	}
 
-	err = advertise.Ports(states)
+	err = putNetmapIpTables(states)
 	if err != nil {
-		logrus.WithFields(logrus.Fields{
-			"error": err,
-		}).Error("state: Failed to advertise ports")
-		err = nil
+		return
 	}
 
-	time.Sleep(200 * time.Millisecond)
-
-	curWgIfaces, activeWgIface, err := GetWgIfaces()
+	err = putMasqueradeIpTables(states)
 	if err != nil {
 		return
 	}
 
-	newWgIfaces := set.NewSet()
-	for _, wgIface := range wgIfaces {
-		newWgIfaces.Add(wgIface)
+	err = putAclIpTables(states)
+	if err != nil {
+		return
 	}
 
-	for _, wgIface := range modWgIfaces {
-		confPth := path.Join(constants.WgDirPath,
-			fmt.Sprintf("%s.conf", wgIface))
+	err = putMssIpTables(states)
+	if err != nil {
+		return
+	}
 
-		_ = utils.Exec("", "wg-quick", "down", wgIface)
-		err = utils.Exec("", "wg-quick", "up", wgIface)
-		if err != nil {
-			logrus.WithFields(logrus.Fields{
-				"wg_iface": wgIface,
-				"wg_conf":  confPth,
-				"error":    err,
-			}).Error("state: Error bringing up wg conf")
-			err = nil
-		}
+	err = advertise.Ports(states)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("state: Failed to advertise ports")
+		err = nil
 	}
 
-	delWgIfaces := curWgIfaces.Copy()
-	delWgIfaces.Subtract(newWgIfaces)
-	for ifaceInf := range delWgIfaces.Iter() {
-		iface := ifaceInf.(string)
-		confPth := path.Join(constants.WgDirPath,
-			fmt.Sprintf("%s.conf", iface))
+	time.Sleep(200 * time.Millisecond)
 
-		err = utils.Exec("", "wg-quick", "down", iface)
-		if err != nil {
-			if activeWgIface.Contains(iface) {
-				logrus.WithFields(logrus.Fields{
-					"wg_iface": iface,
-					"error":    err,
-				}).Error("state: Error bringing down wg conf")
-			}
-			err = nil
-		}
+	err = setWg(wgConfs)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("state: Failed to set wg interfaces")
+		err = nil
+	}
 
-		os.Remove(confPth)
+	err = setXfrm(states)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("state: Failed to set xfrm interfaces")
+		err = nil
 	}
 
 	if restart {
@@ -817,6 +806,22 @@ func deploy(states []*state.State, restart bool) (err error) {
 		err = nil
 	}
 
+	err = setProxy(states)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("state: Failed to set proxy neighbors")
+		err = nil
+	}
+
+	err = setShaping(states)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("state: Failed to set traffic shaping")
+		err = nil
+	}
+
 	isDirectClient := false
 	for _, stat := range states {
 		if stat.Type == state.DirectClient && len(stat.Links) != 0 {
@@ -1002,14 +1007,13 @@ func init() {
 	module.After("logger")
 
 	module.Handler = func() (err error) {
-		wgHash, err = utils.RandStr(32)
-		if err != nil {
-			return
-		}
+		removeWgConfFiles()
 
 		go runDeploy()
 		go runUpdateAdvertise()
 		go runRoutes()
+		go runMtu()
+		go runWgKeys()
 
 		return
 	}
@@ -0,0 +1,96 @@
+package ipsec
+
+import (
+	"time"
+
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/constants"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	wgKeyCheckRate    = 10 * time.Minute
+	wgKeyDefaultGrace = 10 * time.Minute
+)
+
+func getWgKeyGrace() time.Duration {
+	if config.Config.WgKeyGrace > 0 {
+		return time.Duration(config.Config.WgKeyGrace) * time.Second
+	}
+	return wgKeyDefaultGrace
+}
+
+func checkWgKeys() (changed bool) {
+	rotation := time.Duration(config.Config.WgKeyRotation) * 24 * time.Hour
+	grace := getWgKeyGrace()
+
+	for _, stat := range GetStates() {
+		if stat.Protocol != "wg" {
+			continue
+		}
+
+		if config.State.GetNextPublicKey(stat.Id) != "" {
+			promoted, err := config.State.PromoteKey(stat.Id, grace)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"state_id": stat.Id,
+					"error":    err,
+				}).Error("ipsec: Failed to promote wg key")
+				continue
+			}
+
+			if promoted {
+				logrus.WithFields(logrus.Fields{
+					"state_id": stat.Id,
+				}).Info("ipsec: Promoted rotated wg key")
+				changed = true
+			}
+			continue
+		}
+
+		if rotation == 0 {
+			continue
+		}
+
+		age, err := config.State.GetKeyAge(stat.Id)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"state_id": stat.Id,
+				"error":    err,
+			}).Error("ipsec: Failed to get wg key age")
+			continue
+		}
+
+		if age < rotation {
+			continue
+		}
+
+		pubKey, err := config.State.RotateKey(stat.Id)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"state_id": stat.Id,
+				"error":    err,
+			}).Error("ipsec: Failed to rotate wg key")
+			continue
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"state_id":   stat.Id,
+			"public_key": pubKey,
+		}).Info("ipsec: Rotated wg key")
+	}
+
+	return
+}
+
+func runWgKeys() {
+	time.Sleep(30 * time.Second)
+
+	for {
+		if !constants.Interrupt && checkWgKeys() {
+			Redeploy(false)
+		}
+
+		time.Sleep(wgKeyCheckRate)
+	}
+}
@@ -0,0 +1,80 @@
+package ipsec
+
+import (
+	"net"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/iptables"
+	"github.com/pritunl/pritunl-link/state"
+	"github.com/sirupsen/logrus"
+)
+
+func putMasqueradeIpTables(states []*state.State) (err error) {
+	if !config.Config.Masquerade &&
+		len(config.Config.MasqueradeNetworks) == 0 {
+
+		return
+	}
+
+	masqNetworks := set.NewSet()
+	for _, network := range config.Config.MasqueradeNetworks {
+		masqNetworks.Add(network)
+	}
+
+	localAddress := state.GetLocalAddress()
+	address6 := state.GetAddress6()
+	iface := config.Config.MasqueradeInterface
+	if iface == "" {
+		iface = state.GetDefaultInterface()
+	}
+
+	networks := set.NewSet()
+	for _, stat := range states {
+		if stat.Type == state.DirectClient ||
+			stat.Type == state.DirectServer {
+
+			continue
+		}
+
+		for _, link := range stat.Links {
+			for _, network := range link.RightSubnets {
+				if networks.Contains(network) {
+					continue
+				}
+
+				if !config.Config.Masquerade &&
+					!masqNetworks.Contains(network) {
+
+					continue
+				}
+				networks.Add(network)
+
+				ip, _, e := net.ParseCIDR(network)
+				if e != nil {
+					continue
+				}
+
+				address := localAddress
+				if ip.To4() == nil {
+					address = address6
+				}
+
+				if address == "" {
+					logrus.WithFields(logrus.Fields{
+						"network": network,
+					}).Warn("ipsec: Missing address for masquerade")
+					continue
+				}
+
+				err = iptables.SetMasquerade(network, address, iface,
+					config.Config.MasqueradeReverse)
+				if err != nil {
+					return
+				}
+			}
+		}
+	}
+
+	return
+}
@@ -0,0 +1,245 @@
+package ipsec
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/constants"
+	"github.com/pritunl/pritunl-link/iptables"
+	"github.com/pritunl/pritunl-link/state"
+	"github.com/pritunl/pritunl-link/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	mtuMax         = 1500
+	mtuMin         = 1280
+	mtuProbeRate   = 10 * time.Minute
+	ipsecOverhead  = 80
+	ipsecOverhead6 = 100
+	wgOverhead     = 60
+	wgOverhead6    = 80
+	greOverhead    = 24
+)
+
+var (
+	pathMtus     = map[string]int{}
+	pathMtusLock = sync.Mutex{}
+)
+
+func probeMtu(addr string, mtu int) bool {
+	ipv6 := net.ParseIP(addr).To4() == nil
+
+	size := mtu - 28
+	pingExec := "ping"
+	if ipv6 {
+		size = mtu - 48
+		pingExec = "ping6"
+	}
+
+	err := utils.ExecSilent("", pingExec,
+		"-M", "do",
+		"-c", "1",
+		"-W", "1",
+		"-s", fmt.Sprintf("%d", size),
+		addr,
+	)
+
+	return err == nil
+}
+
+func probePathMtu(addr string) (mtu int) {
+	if !probeMtu(addr, mtuMin) {
+		return
+	}
+
+	low := mtuMin
+	high := mtuMax
+	for low < high {
+		mid := (low + high + 1) / 2
+		if probeMtu(addr, mid) {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	mtu = low
+
+	return
+}
+
+func getPathMtu(addr string) int {
+	pathMtusLock.Lock()
+	defer pathMtusLock.Unlock()
+	return pathMtus[addr]
+}
+
+func getMtuOverride(stat *state.State, link *state.Link) (mtu int) {
+	for _, override := range config.Config.Mtus {
+		if override.State != stat.Id {
+			continue
+		}
+
+		if override.Link == link.Id {
+			mtu = override.Mtu
+			return
+		} else if override.Link == "" {
+			mtu = override.Mtu
+		}
+	}
+
+	return
+}
+
+func getLinkMtu(stat *state.State, link *state.Link) (mtu int) {
+	mtu = getMtuOverride(stat, link)
+	if mtu != 0 || !config.Config.MtuDiscovery {
+		return
+	}
+
+	pathMtu := getPathMtu(link.Right)
+	if pathMtu == 0 {
+		return
+	}
+
+	ipv6 := net.ParseIP(link.Right).To4() == nil
+	if stat.Protocol == "wg" {
+		if ipv6 {
+			mtu = pathMtu - wgOverhead6
+		} else {
+			mtu = pathMtu - wgOverhead
+		}
+	} else {
+		if ipv6 {
+			mtu = pathMtu - ipsecOverhead6
+		} else {
+			mtu = pathMtu - ipsecOverhead
+		}
+	}
+
+	return
+}
+
+func getStateMtu(stat *state.State) (mtu int) {
+	if stat.Protocol == "wg" {
+		mtu = config.GetWgData(stat.Id).Mtu
+		if mtu != 0 {
+			return
+		}
+	}
+
+	for _, link := range stat.Links {
+		linkMtu := getLinkMtu(stat, link)
+		if linkMtu != 0 && (mtu == 0 || linkMtu < mtu) {
+			mtu = linkMtu
+		}
+	}
+
+	return
+}
+
+func putMssIpTables(states []*state.State) (err error) {
+	for _, stat := range states {
+		if stat.Type == state.DirectClient ||
+			stat.Type == state.DirectServer {
+
+			continue
+		}
+
+		for _, link := range stat.Links {
+			iface := getLinkIface(stat, link)
+
+			mtu := 0
+			if stat.Protocol == "wg" {
+				mtu = getStateMtu(stat)
+			} else {
+				mtu = getLinkMtu(stat, link)
+			}
+
+			for _, network := range link.RightSubnets {
+				ip, _, e := net.ParseCIDR(network)
+				if e != nil {
+					continue
+				}
+
+				mss := 0
+				if mtu != 0 {
+					mss = mtu - 40
+					if ip.To4() == nil {
+						mss = mtu - 60
+					}
+				}
+
+				err = iptables.SetMssClamp(iface, network, mss)
+				if err != nil {
+					return
+				}
+			}
+		}
+	}
+
+	return
+}
+
+func updatePathMtus() (changed bool) {
+	if !config.Config.MtuDiscovery {
+		return
+	}
+
+	peers := map[string]bool{}
+	for _, stat := range GetStates() {
+		for _, link := range stat.Links {
+			if net.ParseIP(link.Right) != nil {
+				peers[link.Right] = true
+			}
+		}
+	}
+
+	newPathMtus := map[string]int{}
+	for peer := range peers {
+		mtu := probePathMtu(peer)
+		if mtu == 0 {
+			logrus.WithFields(logrus.Fields{
+				"peer": peer,
+			}).Warn("ipsec: Path MTU probe failed")
+			continue
+		}
+
+		newPathMtus[peer] = mtu
+	}
+
+	pathMtusLock.Lock()
+	for peer, mtu := range newPathMtus {
+		if pathMtus[peer] != mtu {
+			logrus.WithFields(logrus.Fields{
+				"peer":     peer,
+				"path_mtu": mtu,
+			}).Info("ipsec: Path MTU changed")
+			changed = true
+		}
+	}
+	for peer := range pathMtus {
+		if _, ok := newPathMtus[peer]; !ok {
+			changed = true
+		}
+	}
+	pathMtus = newPathMtus
+	pathMtusLock.Unlock()
+
+	return
+}
+
+func runMtu() {
+	time.Sleep(30 * time.Second)
+
+	for {
+		if !constants.Interrupt && updatePathMtus() {
+			Redeploy(false)
+		}
+
+		time.Sleep(mtuProbeRate)
+	}
+}
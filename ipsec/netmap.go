@@ -0,0 +1,45 @@
+package ipsec
+
+import (
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/iptables"
+	"github.com/pritunl/pritunl-link/state"
+)
+
+func putNetmapIpTables(states []*state.State) (err error) {
+	if len(config.Config.Netmaps) == 0 {
+		return
+	}
+
+	for _, netmap := range config.Config.Netmaps {
+		remotes := []string{}
+
+		for _, stat := range states {
+			if stat.Type == state.DirectClient ||
+				stat.Type == state.DirectServer {
+
+				continue
+			}
+
+			for _, link := range stat.Links {
+				for _, leftSubnet := range link.LeftSubnets {
+					if leftSubnet == netmap.Mapped {
+						remotes = append(remotes, link.RightSubnets...)
+						break
+					}
+				}
+			}
+		}
+
+		if len(remotes) == 0 {
+			continue
+		}
+
+		err = iptables.SetNetmap(netmap.Network, netmap.Mapped, remotes)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
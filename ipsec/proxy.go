@@ -0,0 +1,264 @@
+package ipsec
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/constants"
+	"github.com/pritunl/pritunl-link/state"
+	"github.com/pritunl/pritunl-link/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const maxProxyBits = 10
+
+var (
+	proxyAddrs       = map[string]string{}
+	proxyInitialized = false
+	proxyLock        = sync.Mutex{}
+)
+
+func getProxyInterface() string {
+	iface := config.Config.ProxyInterface
+	if iface != "" {
+		return iface
+	}
+	return state.GetDefaultInterface()
+}
+
+func getProxyAddrs(states []*state.State) (addrs []string) {
+	addrs = []string{}
+
+	subnets := []*net.IPNet{}
+	for _, stat := range states {
+		if stat.Type == state.DirectClient ||
+			stat.Type == state.DirectServer {
+
+			continue
+		}
+
+		for _, lnk := range stat.Links {
+			for _, network := range lnk.RightSubnets {
+				_, subnet, err := net.ParseCIDR(network)
+				if err != nil {
+					continue
+				}
+				subnets = append(subnets, subnet)
+			}
+		}
+	}
+
+	for _, proxyNetwork := range config.Config.ProxyNetworks {
+		ip, network, err := net.ParseCIDR(proxyNetwork)
+		if err != nil {
+			ip = net.ParseIP(proxyNetwork)
+			if ip == nil {
+				logrus.WithFields(logrus.Fields{
+					"network": proxyNetwork,
+				}).Warn("ipsec: Ignoring invalid proxy network")
+				continue
+			}
+
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+
+			network = &net.IPNet{
+				IP:   ip,
+				Mask: net.CIDRMask(bits, bits),
+			}
+		}
+
+		ones, bits := network.Mask.Size()
+		if bits-ones > maxProxyBits {
+			logrus.WithFields(logrus.Fields{
+				"network": proxyNetwork,
+			}).Warn("ipsec: Ignoring proxy network larger than limit")
+			continue
+		}
+
+		linked := false
+		for _, subnet := range subnets {
+			subnetOnes, subnetBits := subnet.Mask.Size()
+			if subnetBits == bits && subnetOnes <= ones &&
+				subnet.Contains(network.IP) {
+
+				linked = true
+				break
+			}
+		}
+		if !linked {
+			continue
+		}
+
+		broadcast := make(net.IP, len(network.IP))
+		for i := range network.IP {
+			broadcast[i] = network.IP[i] | ^network.Mask[i]
+		}
+		skipEdges := len(network.IP) == net.IPv4len && bits-ones >= 2
+
+		addr := make(net.IP, len(network.IP))
+		copy(addr, network.IP)
+		for network.Contains(addr) {
+			if !skipEdges || (!addr.Equal(network.IP) &&
+				!addr.Equal(broadcast)) {
+
+				addrs = append(addrs, addr.String())
+			}
+
+			for i := len(addr) - 1; i >= 0; i-- {
+				addr[i]++
+				if addr[i] != 0 {
+					break
+				}
+			}
+		}
+	}
+
+	return
+}
+
+func loadProxyAddrs() {
+	data, err := ioutil.ReadFile(constants.ProxyPath)
+	if err != nil {
+		return
+	}
+
+	savedAddrs := map[string]string{}
+	err = json.Unmarshal(data, &savedAddrs)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("ipsec: Failed to parse saved proxy neighbors")
+		return
+	}
+
+	curAddrs := map[string]string{}
+	for _, family := range []string{"-4", "-6"} {
+		output, e := utils.ExecOutput("", "ip", family, "neigh",
+			"show", "proxy")
+		if e != nil {
+			continue
+		}
+
+		for _, line := range strings.Split(output, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 3 || fields[1] != "dev" {
+				continue
+			}
+			curAddrs[fields[0]] = fields[2]
+		}
+	}
+
+	for addr, addrIface := range savedAddrs {
+		if curAddrs[addr] == addrIface {
+			proxyAddrs[addr] = addrIface
+		}
+	}
+}
+
+func saveProxyAddrs() {
+	err := utils.ExistsMkdir(constants.VarDir, 0755)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("ipsec: Failed to create var directory")
+		return
+	}
+
+	data, _ := json.Marshal(proxyAddrs)
+
+	err = ioutil.WriteFile(constants.ProxyPath, data, 0644)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("ipsec: Failed to write proxy neighbors")
+		return
+	}
+}
+
+func setProxy(states []*state.State) (err error) {
+	iface := getProxyInterface()
+	newAddrs := map[string]string{}
+
+	if iface != "" {
+		for _, addr := range getProxyAddrs(states) {
+			newAddrs[addr] = iface
+		}
+	}
+
+	proxyLock.Lock()
+	defer proxyLock.Unlock()
+
+	if !proxyInitialized {
+		loadProxyAddrs()
+		proxyInitialized = true
+	}
+	defer saveProxyAddrs()
+
+	for addr, addrIface := range proxyAddrs {
+		if newAddrs[addr] == addrIface {
+			continue
+		}
+
+		utils.ExecSilent("",
+			"ip", "neigh",
+			"del", "proxy", addr,
+			"dev", addrIface,
+		)
+		delete(proxyAddrs, addr)
+	}
+
+	ndpIfaces := map[string]bool{}
+	for addr, addrIface := range newAddrs {
+		if _, ok := proxyAddrs[addr]; ok {
+			continue
+		}
+
+		if net.ParseIP(addr).To4() == nil && !ndpIfaces[addrIface] {
+			err = utils.ExecSilent("",
+				"sysctl", "-w",
+				"net.ipv6.conf."+addrIface+".proxy_ndp=1",
+			)
+			if err != nil {
+				return
+			}
+			ndpIfaces[addrIface] = true
+		}
+
+		err = utils.Exec("",
+			"ip", "neigh",
+			"replace", "proxy", addr,
+			"dev", addrIface,
+		)
+		if err != nil {
+			return
+		}
+
+		proxyAddrs[addr] = addrIface
+	}
+
+	return
+}
+
+func ClearProxy() {
+	proxyLock.Lock()
+	defer proxyLock.Unlock()
+
+	for addr, addrIface := range proxyAddrs {
+		utils.ExecSilent("",
+			"ip", "neigh",
+			"del", "proxy", addr,
+			"dev", addrIface,
+		)
+	}
+
+	proxyAddrs = map[string]string{}
+	saveProxyAddrs()
+}
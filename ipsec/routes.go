@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/pritunl/pritunl-link/config"
 	"github.com/pritunl/pritunl-link/constants"
 	"github.com/pritunl/pritunl-link/state"
 	"github.com/pritunl/pritunl-link/status"
@@ -31,14 +32,17 @@ func getDirectStatus(stat *state.State) (directStatus bool, err error) {
 		linkId := fmt.Sprintf("%s-%s-%s", stat.Id, stat.Links[0].Id, stat.Hash)
 
 		wgKeyMap := map[string]string{}
+		wgTimeouts := map[string]time.Duration{}
 		for _, lnk := range stat.Links {
 			if stat.Protocol == "wg" {
 				wgKeyMap[lnk.WgPublicKey] = fmt.Sprintf(
 					"%s-%s", stat.Id, lnk.Id, stat.Hash)
+				wgTimeouts[lnk.WgPublicKey] = time.Duration(
+					config.GetWgData(stat.Id).HandshakeTimeout) * time.Second
 			}
 		}
 
-		stats, err = status.GetWg(wgKeyMap)
+		stats, err = status.GetWg(wgKeyMap, wgTimeouts)
 		if err != nil {
 			return
 		}
@@ -0,0 +1,402 @@
+package ipsec
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/iptables"
+	"github.com/pritunl/pritunl-link/state"
+	"github.com/pritunl/pritunl-link/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	shapeHandle        = "9790:"
+	shapeDefaultUplink = 10000000
+	shapeDefaultClass  = 9999
+	shapePrio          = 9790
+	shapePrio6         = 9791
+	shapeMark          = 0x979000
+)
+
+var (
+	shapeIfaces      = map[string]string{}
+	shapeInitialized = false
+	shapeLock        = sync.Mutex{}
+)
+
+type shapeIface struct {
+	Tunnel  bool
+	Classes []*shapeClass
+}
+
+type shapeClass struct {
+	Class    int
+	Egress   int
+	Ingress  int
+	Priority int
+	Peers    []string
+	Networks []string
+}
+
+func getShapeUplink() int {
+	if config.Config.ShapeUplink > 0 {
+		return config.Config.ShapeUplink
+	}
+	return shapeDefaultUplink
+}
+
+func getShapeBurst(rate int) string {
+	burst := rate * 1000 / 8 / 10
+	if burst < 16000 {
+		burst = 16000
+	}
+	return fmt.Sprintf("%d", burst)
+}
+
+func getShapeIfaces(states []*state.State) (
+	ifaces map[string]*shapeIface) {
+
+	ifaces = map[string]*shapeIface{}
+
+	shapes := map[string]*config.ShapeData{}
+	for _, shape := range config.Config.Shapes {
+		shapes[shape.State] = shape
+	}
+
+	defaultIface := state.GetDefaultInterface()
+
+	for i, stat := range states {
+		shape := shapes[stat.Id]
+		if shape == nil || (shape.Egress == 0 && shape.Ingress == 0 &&
+			shape.Priority == 0) {
+
+			continue
+		}
+
+		class := &shapeClass{
+			Class:    10 + i,
+			Egress:   shape.Egress,
+			Ingress:  shape.Ingress,
+			Priority: shape.Priority,
+		}
+
+		if stat.Protocol == "wg" {
+			ifaces[GetWgIface(stat.Id)] = &shapeIface{
+				Tunnel:  true,
+				Classes: []*shapeClass{class},
+			}
+			continue
+		}
+
+		if isRouteBased(stat) {
+			for _, link := range stat.Links {
+				ifaces[GetXfrmIface(stat.Id, link.Id)] = &shapeIface{
+					Tunnel:  true,
+					Classes: []*shapeClass{class},
+				}
+			}
+			continue
+		}
+
+		if defaultIface == "" {
+			continue
+		}
+
+		for _, link := range stat.Links {
+			if net.ParseIP(link.Right) != nil {
+				class.Peers = append(class.Peers, link.Right)
+			}
+
+			for _, network := range link.RightSubnets {
+				_, _, e := net.ParseCIDR(network)
+				if e == nil {
+					class.Networks = append(class.Networks, network)
+				}
+			}
+		}
+		sort.Strings(class.Peers)
+		sort.Strings(class.Networks)
+
+		if len(class.Networks) != 0 {
+			iface := ifaces[defaultIface]
+			if iface == nil {
+				iface = &shapeIface{}
+				ifaces[defaultIface] = iface
+			}
+			iface.Classes = append(iface.Classes, class)
+		}
+	}
+
+	return
+}
+
+func getShapeSpec(iface *shapeIface) string {
+	spec := fmt.Sprintf("%d", getShapeUplink())
+	for _, class := range iface.Classes {
+		spec += fmt.Sprintf(";%d,%d,%d,%d,%s,%s", class.Class, class.Egress,
+			class.Ingress, class.Priority, strings.Join(class.Peers, ","),
+			strings.Join(class.Networks, ","))
+	}
+	return spec
+}
+
+func hasShaping(iface string) bool {
+	output, err := utils.ExecOutput("", "tc", "qdisc", "show", "dev", iface)
+	if err != nil {
+		return false
+	}
+	return strings.Contains(output, "htb "+shapeHandle)
+}
+
+func hasForeignQdisc(iface string) bool {
+	output, err := utils.ExecOutput("", "tc", "qdisc", "show", "dev",
+		iface, "root")
+	if err != nil {
+		return false
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[0] != "qdisc" || fields[3] != "root" {
+			continue
+		}
+
+		if fields[2] != "0:" && fields[2] != shapeHandle {
+			return true
+		}
+	}
+
+	return false
+}
+
+func clearShaping(iface string) {
+	if hasShaping(iface) {
+		utils.ExecSilent("", "tc", "qdisc", "del", "dev", iface, "root")
+	}
+
+	for _, prio := range []int{shapePrio, shapePrio6} {
+		utils.ExecSilent("", "tc", "filter", "del", "dev", iface,
+			"parent", "ffff:", "prio", fmt.Sprintf("%d", prio))
+	}
+}
+
+func putShaping(iface string, shapeIfc *shapeIface) (err error) {
+	uplink := fmt.Sprintf("%dkbit", getShapeUplink())
+	tunnel := shapeIfc.Tunnel
+
+	defaultClass := shapeDefaultClass
+	if tunnel {
+		defaultClass = shapeIfc.Classes[0].Class
+	}
+
+	err = utils.Exec("", "tc", "qdisc", "replace", "dev", iface,
+		"root", "handle", shapeHandle, "htb",
+		"default", fmt.Sprintf("%d", defaultClass))
+	if err != nil {
+		return
+	}
+
+	err = utils.Exec("", "tc", "class", "replace", "dev", iface,
+		"parent", shapeHandle, "classid", shapeHandle+"1",
+		"htb", "rate", uplink, "ceil", uplink)
+	if err != nil {
+		return
+	}
+
+	if !tunnel {
+		err = utils.Exec("", "tc", "class", "replace", "dev", iface,
+			"parent", shapeHandle+"1",
+			"classid", fmt.Sprintf("%s%d", shapeHandle, shapeDefaultClass),
+			"htb", "rate", fmt.Sprintf("%dkbit", getShapeUplink()/10),
+			"ceil", uplink, "prio", "4")
+		if err != nil {
+			return
+		}
+	}
+
+	ingress := false
+	for _, class := range shapeIfc.Classes {
+		classId := fmt.Sprintf("%s%d", shapeHandle, class.Class)
+
+		ceil := uplink
+		rate := fmt.Sprintf("%dkbit", getShapeUplink()/10)
+		if class.Egress != 0 {
+			ceil = fmt.Sprintf("%dkbit", class.Egress)
+			rate = ceil
+		}
+
+		err = utils.Exec("", "tc", "class", "replace", "dev", iface,
+			"parent", shapeHandle+"1", "classid", classId,
+			"htb", "rate", rate, "ceil", ceil,
+			"prio", fmt.Sprintf("%d", class.Priority))
+		if err != nil {
+			return
+		}
+
+		err = utils.Exec("", "tc", "qdisc", "replace", "dev", iface,
+			"parent", classId, "fq_codel")
+		if err != nil {
+			return
+		}
+
+		if class.Ingress != 0 && !ingress {
+			utils.ExecSilent("", "tc", "qdisc", "add", "dev", iface,
+				"handle", "ffff:", "ingress")
+			ingress = true
+		}
+
+		police := []string{
+			"action", "police",
+			"rate", fmt.Sprintf("%dkbit", class.Ingress),
+			"burst", getShapeBurst(class.Ingress),
+			"drop",
+		}
+
+		if tunnel {
+			if class.Ingress != 0 {
+				args := []string{
+					"filter", "add", "dev", iface,
+					"parent", "ffff:", "protocol", "all",
+					"prio", fmt.Sprintf("%d", shapePrio),
+					"matchall",
+				}
+				args = append(args, police...)
+
+				err = utils.Exec("", "tc", args...)
+				if err != nil {
+					return
+				}
+			}
+			continue
+		}
+
+		err = utils.Exec("", "tc", "filter", "add", "dev", iface,
+			"parent", shapeHandle, "protocol", "all",
+			"prio", fmt.Sprintf("%d", shapePrio),
+			"handle", fmt.Sprintf("0x%x", shapeMark+class.Class), "fw",
+			"classid", classId)
+		if err != nil {
+			return
+		}
+
+		if class.Ingress == 0 {
+			continue
+		}
+
+		for _, peer := range class.Peers {
+			proto := "ip"
+			prio := shapePrio
+			if net.ParseIP(peer).To4() == nil {
+				proto = "ipv6"
+				prio = shapePrio6
+			}
+
+			args := []string{
+				"filter", "add", "dev", iface,
+				"parent", "ffff:", "protocol", proto,
+				"prio", fmt.Sprintf("%d", prio),
+				"flower", "src_ip", peer,
+			}
+			args = append(args, police...)
+
+			err = utils.Exec("", "tc", args...)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
+
+func putShapeMarks(shapeIfc *shapeIface) (err error) {
+	if shapeIfc.Tunnel {
+		return
+	}
+
+	for _, class := range shapeIfc.Classes {
+		for _, network := range class.Networks {
+			err = iptables.SetShapeMark(network, shapeMark+class.Class)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
+
+func setShaping(states []*state.State) (err error) {
+	shapeLock.Lock()
+	defer shapeLock.Unlock()
+
+	ifaces := getShapeIfaces(states)
+
+	if !shapeInitialized {
+		defaultIface := state.GetDefaultInterface()
+		if defaultIface != "" {
+			clearShaping(defaultIface)
+		}
+		shapeInitialized = true
+	}
+
+	for iface := range shapeIfaces {
+		if _, ok := ifaces[iface]; !ok {
+			clearShaping(iface)
+			delete(shapeIfaces, iface)
+		}
+	}
+
+	for iface, shapeIfc := range ifaces {
+		err = putShapeMarks(shapeIfc)
+		if err != nil {
+			return
+		}
+
+		spec := getShapeSpec(shapeIfc)
+		if shapeIfaces[iface] == spec && hasShaping(iface) {
+			continue
+		}
+
+		if hasForeignQdisc(iface) {
+			logrus.WithFields(logrus.Fields{
+				"interface": iface,
+			}).Warn("ipsec: Existing root qdisc, skipping traffic shaping")
+			continue
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"interface": iface,
+			"classes":   len(shapeIfc.Classes),
+		}).Info("ipsec: Applying link traffic shaping")
+
+		clearShaping(iface)
+		delete(shapeIfaces, iface)
+
+		err = putShaping(iface, shapeIfc)
+		if err != nil {
+			clearShaping(iface)
+			return
+		}
+
+		shapeIfaces[iface] = spec
+	}
+
+	return
+}
+
+func ClearShaping() {
+	shapeLock.Lock()
+	defer shapeLock.Unlock()
+
+	for iface := range shapeIfaces {
+		clearShaping(iface)
+	}
+
+	shapeIfaces = map[string]string{}
+}
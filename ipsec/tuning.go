@@ -0,0 +1,161 @@
+package ipsec
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/errortypes"
+	"github.com/pritunl/pritunl-link/state"
+	"github.com/sirupsen/logrus"
+)
+
+var tuningDurationRe = regexp.MustCompile(`^[0-9]{1,6}[smhd]?$`)
+
+type tuning struct {
+	IkeLifetime string
+	KeyLife     string
+	RekeyMargin string
+	DpdDelay    string
+	DpdTimeout  string
+	Mobike      string
+	Auto        string
+}
+
+func parseTuningDuration(value string) (secs int) {
+	if !tuningDurationRe.MatchString(value) {
+		return -1
+	}
+
+	unit := value[len(value)-1]
+	if unit >= '0' && unit <= '9' {
+		secs, _ = strconv.Atoi(value)
+		return
+	}
+
+	secs, _ = strconv.Atoi(value[:len(value)-1])
+	switch unit {
+	case 'm':
+		secs *= 60
+	case 'h':
+		secs *= 3600
+	case 'd':
+		secs *= 86400
+	}
+
+	return
+}
+
+func ValidTuning(key, value string) bool {
+	switch key {
+	case "ike_lifetime", "key_life":
+		return parseTuningDuration(value) > 0
+	case "rekey_margin", "dpd_delay", "dpd_timeout":
+		return parseTuningDuration(value) >= 0
+	case "mobike":
+		return value == "yes" || value == "no"
+	case "auto":
+		return value == "start" || value == "route" || value == "add"
+	}
+
+	return false
+}
+
+func mergeTuning(tun *tuning, data *config.TuningData) {
+	if data.IkeLifetime != "" {
+		tun.IkeLifetime = data.IkeLifetime
+	}
+	if data.KeyLife != "" {
+		tun.KeyLife = data.KeyLife
+	}
+	if data.RekeyMargin != "" {
+		tun.RekeyMargin = data.RekeyMargin
+	}
+	if data.DpdDelay != "" {
+		tun.DpdDelay = data.DpdDelay
+	}
+	if data.DpdTimeout != "" {
+		tun.DpdTimeout = data.DpdTimeout
+	}
+	if data.Mobike != "" {
+		tun.Mobike = data.Mobike
+	}
+	if data.Auto != "" {
+		tun.Auto = data.Auto
+	}
+}
+
+func (t *tuning) Validate() (err error) {
+	ikeLifetime := parseTuningDuration(t.IkeLifetime)
+	keyLife := parseTuningDuration(t.KeyLife)
+	rekeyMargin := parseTuningDuration(t.RekeyMargin)
+	dpdDelay := parseTuningDuration(t.DpdDelay)
+	dpdTimeout := parseTuningDuration(t.DpdTimeout)
+
+	if ikeLifetime <= 0 || keyLife <= 0 || rekeyMargin < 0 ||
+		dpdDelay < 0 || dpdTimeout < 0 {
+
+		err = &errortypes.ParseError{
+			errors.New("ipsec: Invalid tuning duration"),
+		}
+		return
+	}
+
+	if rekeyMargin >= keyLife || rekeyMargin >= ikeLifetime {
+		err = &errortypes.ParseError{
+			errors.New("ipsec: Tuning rekey margin exceeds lifetime"),
+		}
+		return
+	}
+
+	if dpdDelay != 0 && dpdTimeout <= dpdDelay {
+		err = &errortypes.ParseError{
+			errors.New("ipsec: Tuning dpd timeout must exceed dpd delay"),
+		}
+		return
+	}
+
+	return
+}
+
+func getDefaultTuning() *tuning {
+	return &tuning{
+		IkeLifetime: "8h",
+		KeyLife:     "1h",
+		RekeyMargin: "9m",
+		DpdDelay:    "5s",
+		DpdTimeout:  "15s",
+		Mobike:      "yes",
+		Auto:        "start",
+	}
+}
+
+func getTuning(stat *state.State, link *state.Link) (tun *tuning) {
+	tun = getDefaultTuning()
+
+	for _, data := range config.Config.Tunings {
+		if data.State == stat.Id && data.Link == "" {
+			mergeTuning(tun, data)
+		}
+	}
+
+	for _, data := range config.Config.Tunings {
+		if data.State == stat.Id && data.Link == link.Id {
+			mergeTuning(tun, data)
+		}
+	}
+
+	err := tun.Validate()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"state_id": stat.Id,
+			"link_id":  link.Id,
+			"error":    err,
+		}).Error("ipsec: Invalid link tuning, using defaults")
+
+		tun = getDefaultTuning()
+	}
+
+	return
+}
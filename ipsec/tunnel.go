@@ -1,14 +1,11 @@
 package ipsec
 
 import (
-	"fmt"
 	"net"
-	"os"
-	"path"
+	"strconv"
 	"strings"
 
 	"github.com/dropbox/godropbox/errors"
-	"github.com/pritunl/pritunl-link/constants"
 	"github.com/pritunl/pritunl-link/errortypes"
 	"github.com/pritunl/pritunl-link/state"
 	"github.com/pritunl/pritunl-link/utils"
@@ -18,8 +15,26 @@ import (
 var (
 	tunnelLocal  = ""
 	tunnelRemote = ""
+	tunnelMtu    = 0
 )
 
+func setTunnelMtu(mtu int) (err error) {
+	if mtu == 0 || mtu == tunnelMtu {
+		return
+	}
+
+	err = utils.Exec("",
+		"ip", "link",
+		"set", DirectIface, "mtu", strconv.Itoa(mtu),
+	)
+	if err != nil {
+		return
+	}
+	tunnelMtu = mtu
+
+	return
+}
+
 func StartTunnel(stat *state.State) (err error) {
 	if GetDirectMode() != DirectGre {
 		StopTunnel()
@@ -42,7 +57,16 @@ func StartTunnel(stat *state.State) (err error) {
 	newTunnelLocal := state.GetLocalAddress()
 	newTunnelRemote := peerLocal
 
+	newTunnelMtu := getLinkMtu(stat, stat.Links[0])
+	if newTunnelMtu != 0 {
+		newTunnelMtu -= greOverhead
+	}
+
 	if newTunnelLocal == tunnelLocal && newTunnelRemote == tunnelRemote {
+		err = setTunnelMtu(newTunnelMtu)
+		if err != nil {
+			return
+		}
 		return
 	}
 	StopTunnel()
@@ -78,6 +102,11 @@ func StartTunnel(stat *state.State) (err error) {
 		return
 	}
 
+	err = setTunnelMtu(newTunnelMtu)
+	if err != nil {
+		return
+	}
+
 	var directAddrIp net.IP
 	if stat.Type == state.DirectClient {
 		directAddrIp, err = GetDirectClientIp()
@@ -115,30 +144,32 @@ func StopTunnel() {
 	)
 	tunnelLocal = ""
 	tunnelRemote = ""
+	tunnelMtu = 0
 }
 
 func StopWg() {
-	curWgIfaces, activeWgIfaces, err := GetWgIfaces()
+	wgRoutesLock.Lock()
+	defer wgRoutesLock.Unlock()
+
+	curWgIfaces, _, err := GetWgIfaces()
 	if err != nil {
 		return
 	}
 
-	for ifaceInf := range curWgIfaces.Iter() {
-		iface := ifaceInf.(string)
-		confPth := path.Join(constants.WgDirPath,
-			fmt.Sprintf("%s.conf", iface))
+	userspaceDevicesLock.Lock()
+	for iface := range userspaceDevices {
+		curWgIfaces.Add(iface)
+	}
+	userspaceDevicesLock.Unlock()
 
-		err = utils.Exec("", "wg-quick", "down", iface)
-		if err != nil {
-			if activeWgIfaces.Contains(iface) {
-				logrus.WithFields(logrus.Fields{
-					"wg_iface": iface,
-					"error":    err,
-				}).Error("state: Error bringing down wg conf")
-			}
-			err = nil
-		}
+	for ifaceInf := range curWgIfaces.Iter() {
+		removeWgIface(ifaceInf.(string))
+	}
 
-		os.Remove(confPth)
+	for network, def := range wgDefaults {
+		clearWgDefault(network, def)
 	}
+
+	wgRoutes = map[string]*wgRoute{}
+	wgDefaults = map[string]*wgDefault{}
 }
@@ -0,0 +1,129 @@
+package ipsec
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/errortypes"
+	"github.com/sirupsen/logrus"
+	"golang.zx2c4.com/wireguard/conn"
+	"golang.zx2c4.com/wireguard/device"
+	"golang.zx2c4.com/wireguard/ipc"
+	"golang.zx2c4.com/wireguard/tun"
+)
+
+var (
+	userspaceDevices     = map[string]*userspaceDevice{}
+	userspaceDevicesLock = sync.Mutex{}
+)
+
+type userspaceDevice struct {
+	Device   *device.Device
+	Listener net.Listener
+}
+
+func (d *userspaceDevice) Close() {
+	d.Listener.Close()
+	d.Device.Close()
+}
+
+func getUserspaceLogger(iface string) *device.Logger {
+	return &device.Logger{
+		Verbosef: device.DiscardLogf,
+		Errorf: func(format string, args ...interface{}) {
+			logrus.WithFields(logrus.Fields{
+				"wg_iface": iface,
+				"error":    fmt.Sprintf(format, args...),
+			}).Error("ipsec: Userspace wg error")
+		},
+	}
+}
+
+func isUserspaceWg(iface string) bool {
+	userspaceDevicesLock.Lock()
+	_, ok := userspaceDevices[iface]
+	userspaceDevicesLock.Unlock()
+	return ok
+}
+
+func createUserspaceWg(iface string) (err error) {
+	userspaceDevicesLock.Lock()
+	defer userspaceDevicesLock.Unlock()
+
+	if _, ok := userspaceDevices[iface]; ok {
+		return
+	}
+
+	tunDevice, err := tun.CreateTUN(iface, wgDefaultMtu)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "ipsec: Failed to create wg tun device"),
+		}
+		return
+	}
+
+	uapiFile, err := ipc.UAPIOpen(iface)
+	if err != nil {
+		tunDevice.Close()
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "ipsec: Failed to open wg uapi socket"),
+		}
+		return
+	}
+
+	dev := device.NewDevice(tunDevice, conn.NewDefaultBind(),
+		getUserspaceLogger(iface))
+
+	listener, err := ipc.UAPIListen(iface, uapiFile)
+	if err != nil {
+		dev.Close()
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "ipsec: Failed to listen on wg uapi socket"),
+		}
+		return
+	}
+
+	go func() {
+		for {
+			uapiConn, e := listener.Accept()
+			if e != nil {
+				return
+			}
+			go dev.IpcHandle(uapiConn)
+		}
+	}()
+
+	err = dev.Up()
+	if err != nil {
+		listener.Close()
+		dev.Close()
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "ipsec: Failed to start wg userspace device"),
+		}
+		return
+	}
+
+	userspaceDevices[iface] = &userspaceDevice{
+		Device:   dev,
+		Listener: listener,
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"wg_iface": iface,
+	}).Info("ipsec: Created userspace wg interface")
+
+	return
+}
+
+func removeUserspaceWg(iface string) {
+	userspaceDevicesLock.Lock()
+	dev := userspaceDevices[iface]
+	delete(userspaceDevices, iface)
+	userspaceDevicesLock.Unlock()
+
+	if dev != nil {
+		dev.Close()
+	}
+}
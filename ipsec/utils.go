@@ -7,14 +7,12 @@ import (
 	"encoding/base64"
 	"fmt"
 	"net"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/dropbox/godropbox/container/set"
 	"github.com/dropbox/godropbox/errors"
 	"github.com/pritunl/pritunl-link/config"
-	"github.com/pritunl/pritunl-link/constants"
 	"github.com/pritunl/pritunl-link/errortypes"
 	"github.com/pritunl/pritunl-link/utils"
 )
@@ -43,21 +41,6 @@ func GetWgIfaces() (ifacesSet set.Set, activeIfacesSet set.Set, err error) {
 		}
 	}
 
-	files, err := os.ReadDir(constants.WgDirPath)
-	if err != nil {
-		err = nil
-	} else {
-		for _, file := range files {
-			filename := file.Name()
-			if strings.HasPrefix(filename, "wgp") &&
-				strings.HasSuffix(filename, ".conf") {
-
-				ifaceName := strings.TrimSuffix(filename, ".conf")
-				ifacesSet.Add(ifaceName)
-			}
-		}
-	}
-
 	return
 }
 
@@ -140,3 +123,20 @@ func GetWgIface(id string) string {
 	hashSum := base32.StdEncoding.EncodeToString(hash.Sum(nil))[:11]
 	return fmt.Sprintf("wgp%s", strings.ToLower(hashSum))
 }
+
+func GetXfrmIface(stateId, linkId string) string {
+	hash := md5.New()
+	hash.Write([]byte(stateId + "-" + linkId))
+	hashSum := base32.StdEncoding.EncodeToString(hash.Sum(nil))[:11]
+	return fmt.Sprintf("xfp%s", strings.ToLower(hashSum))
+}
+
+func GetXfrmIfId(stateId, linkId string) uint32 {
+	hash := md5.Sum([]byte(stateId + "-" + linkId))
+	ifId := (uint32(hash[0])<<24 | uint32(hash[1])<<16 |
+		uint32(hash[2])<<8 | uint32(hash[3])) & 0x7fffffff
+	if ifId == 0 {
+		ifId = 1
+	}
+	return ifId
+}
@@ -0,0 +1,521 @@
+package ipsec
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/constants"
+	"github.com/pritunl/pritunl-link/errortypes"
+	"github.com/pritunl/pritunl-link/state"
+	"github.com/pritunl/pritunl-link/utils"
+	"github.com/sirupsen/logrus"
+	"golang.zx2c4.com/wireguard/wgctrl"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+const (
+	wgTable      = 51820
+	wgDefaultMtu = 1420
+	wgKeepalive  = 25
+)
+
+var (
+	wgRoutes      = map[string]*wgRoute{}
+	wgDefaults    = map[string]*wgDefault{}
+	wgRoutesLock  = sync.Mutex{}
+	wgDefaultNets = []string{"0.0.0.0/0", "::/0"}
+)
+
+type wgConf struct {
+	StateId    string
+	Iface      string
+	PrivateKey string
+	Port       int
+	Mtu        int
+	FwMark     int
+	Table      string
+	Peers      []*wgPeer
+}
+
+type wgRoute struct {
+	Network string
+	Iface   string
+	Table   string
+}
+
+func (r *wgRoute) Key() string {
+	return r.Network + " " + r.Table
+}
+
+func (r *wgRoute) args(action string) (args []string) {
+	args = []string{"route", action, r.Network, "dev", r.Iface}
+	if r.Table != "" {
+		args = append(args, "table", r.Table)
+	}
+	return
+}
+
+type wgDefault struct {
+	Iface  string
+	FwMark int
+}
+
+type wgPeer struct {
+	PublicKey    string
+	PreSharedKey string
+	Endpoint     string
+	AllowedIps   []string
+	Keepalive    int
+}
+
+func isDefaultNetwork(network string) bool {
+	_, ipNet, err := net.ParseCIDR(network)
+	if err != nil {
+		return false
+	}
+
+	ones, _ := ipNet.Mask.Size()
+	return ones == 0
+}
+
+func (p *wgPeer) config() (peerConf wgtypes.PeerConfig, err error) {
+	pubKey, err := wgtypes.ParseKey(p.PublicKey)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "ipsec: Failed to parse wg public key"),
+		}
+		return
+	}
+
+	preSharedKey, err := wgtypes.ParseKey(p.PreSharedKey)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "ipsec: Failed to parse wg pre shared key"),
+		}
+		return
+	}
+
+	endpoint, err := net.ResolveUDPAddr("udp", p.Endpoint)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "ipsec: Failed to resolve wg endpoint"),
+		}
+		return
+	}
+
+	allowedIps := []net.IPNet{}
+	for _, network := range p.AllowedIps {
+		_, ipNet, e := net.ParseCIDR(network)
+		if e != nil {
+			err = &errortypes.ParseError{
+				errors.Wrap(e, "ipsec: Failed to parse wg allowed ip"),
+			}
+			return
+		}
+		allowedIps = append(allowedIps, *ipNet)
+	}
+
+	keepalive := time.Duration(p.Keepalive) * time.Second
+
+	peerConf = wgtypes.PeerConfig{
+		PublicKey:                   pubKey,
+		PresharedKey:                &preSharedKey,
+		Endpoint:                    endpoint,
+		PersistentKeepaliveInterval: &keepalive,
+		ReplaceAllowedIPs:           true,
+		AllowedIPs:                  allowedIps,
+	}
+
+	return
+}
+
+func (c *wgConf) config(device *wgtypes.Device) (
+	conf wgtypes.Config, err error) {
+
+	privKey, err := wgtypes.ParseKey(c.PrivateKey)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "ipsec: Failed to parse wg private key"),
+		}
+		return
+	}
+
+	port := c.Port
+	fwMark := c.getFwMark()
+
+	conf = wgtypes.Config{
+		PrivateKey:   &privKey,
+		ListenPort:   &port,
+		FirewallMark: &fwMark,
+	}
+
+	peerKeys := map[wgtypes.Key]bool{}
+	for _, peer := range c.Peers {
+		peerConf, e := peer.config()
+		if e != nil {
+			err = e
+			return
+		}
+
+		peerKeys[peerConf.PublicKey] = true
+		conf.Peers = append(conf.Peers, peerConf)
+	}
+
+	if device != nil {
+		for _, peer := range device.Peers {
+			if !peerKeys[peer.PublicKey] {
+				conf.Peers = append(conf.Peers, wgtypes.PeerConfig{
+					PublicKey: peer.PublicKey,
+					Remove:    true,
+				})
+			}
+		}
+	}
+
+	return
+}
+
+func (c *wgConf) getFwMark() int {
+	if c.FwMark != 0 {
+		return c.FwMark
+	}
+	if c.Table == "" && c.hasDefault() {
+		return wgTable
+	}
+	return 0
+}
+
+func (c *wgConf) hasDefault() bool {
+	for _, peer := range c.Peers {
+		for _, network := range peer.AllowedIps {
+			if isDefaultNetwork(network) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func getIpCmd(network string) []string {
+	if strings.Contains(network, ":") {
+		return []string{"-6"}
+	}
+	return []string{"-4"}
+}
+
+func addWgDefault(defaults map[string]*wgDefault, network string,
+	def *wgDefault) {
+
+	curDef := defaults[network]
+	if curDef != nil {
+		if curDef.Iface != def.Iface {
+			logrus.WithFields(logrus.Fields{
+				"network":       network,
+				"wg_iface":      curDef.Iface,
+				"ignored_iface": def.Iface,
+			}).Warn("ipsec: Multiple wg default routes, ignoring conflict")
+		}
+		return
+	}
+
+	defaults[network] = def
+}
+
+func setWgDefault(network string, def *wgDefault) (err error) {
+	args := getIpCmd(network)
+	table := fmt.Sprintf("%d", def.FwMark)
+
+	curDef := wgDefaults[network]
+	if curDef != nil && curDef.FwMark != def.FwMark {
+		clearWgDefault(network, curDef)
+		curDef = nil
+	}
+
+	err = utils.Exec("", "ip", append(args, "route", "replace", network,
+		"dev", def.Iface, "table", table)...)
+	if err != nil {
+		return
+	}
+
+	if curDef != nil {
+		return
+	}
+
+	utils.ExecSilent("", "ip", append(args, "rule", "del", "not", "fwmark",
+		table, "table", table)...)
+	utils.ExecSilent("", "ip", append(args, "rule", "del", "table", "main",
+		"suppress_prefixlength", "0")...)
+
+	err = utils.Exec("", "ip", append(args, "rule", "add", "not", "fwmark",
+		table, "table", table)...)
+	if err != nil {
+		return
+	}
+
+	err = utils.Exec("", "ip", append(args, "rule", "add", "table", "main",
+		"suppress_prefixlength", "0")...)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func clearWgDefault(network string, def *wgDefault) {
+	args := getIpCmd(network)
+	table := fmt.Sprintf("%d", def.FwMark)
+
+	utils.ExecSilent("", "ip", append(args, "route", "flush",
+		"table", table)...)
+	utils.ExecSilent("", "ip", append(args, "rule", "del", "not", "fwmark",
+		table, "table", table)...)
+	utils.ExecSilent("", "ip", append(args, "rule", "del", "table", "main",
+		"suppress_prefixlength", "0")...)
+}
+
+func createWgIface(iface string) (err error) {
+	e := utils.Exec("", "ip", "link", "add", "dev", iface,
+		"type", "wireguard")
+	if e != nil {
+		logrus.WithFields(logrus.Fields{
+			"wg_iface": iface,
+			"error":    e,
+		}).Warn("ipsec: Kernel wg unavailable, using userspace")
+
+		err = createUserspaceWg(iface)
+		if err != nil {
+			return
+		}
+
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"wg_iface": iface,
+	}).Info("ipsec: Created wg interface")
+
+	return
+}
+
+func removeWgIface(iface string) {
+	if isUserspaceWg(iface) {
+		removeUserspaceWg(iface)
+	} else {
+		utils.ExecSilent("", "ip", "link", "del", "dev", iface)
+	}
+}
+
+func removeWgConfFiles() {
+	files, err := os.ReadDir(constants.WgDirPath)
+	if err != nil {
+		return
+	}
+
+	for _, file := range files {
+		filename := file.Name()
+		if strings.HasPrefix(filename, "wgp") &&
+			strings.HasSuffix(filename, ".conf") {
+
+			os.Remove(path.Join(constants.WgDirPath, filename))
+		}
+	}
+}
+
+func configureWg(client *wgctrl.Client, conf *wgConf) (err error) {
+	device, err := client.Device(conf.Iface)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			err = &errortypes.ReadError{
+				errors.Wrap(err, "ipsec: Failed to get wg device"),
+			}
+			return
+		}
+
+		err = createWgIface(conf.Iface)
+		if err != nil {
+			return
+		}
+		device = nil
+	}
+
+	wgConfig, err := conf.config(device)
+	if err != nil {
+		return
+	}
+
+	err = client.ConfigureDevice(conf.Iface, wgConfig)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "ipsec: Failed to configure wg device"),
+		}
+		return
+	}
+
+	mtu := conf.Mtu
+	if mtu == 0 {
+		mtu = wgDefaultMtu
+	}
+
+	err = utils.Exec("", "ip", "link", "set", "dev", conf.Iface,
+		"mtu", fmt.Sprintf("%d", mtu), "up")
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func setWg(wgConfs map[string]*wgConf) (err error) {
+	wgRoutesLock.Lock()
+	defer wgRoutesLock.Unlock()
+
+	curIfaces, _, err := GetWgIfaces()
+	if err != nil {
+		return
+	}
+
+	userspaceDevicesLock.Lock()
+	for iface := range userspaceDevices {
+		curIfaces.Add(iface)
+	}
+	userspaceDevicesLock.Unlock()
+
+	wgModes := map[string]string{}
+	newRoutes := map[string]*wgRoute{}
+	newDefaults := map[string]*wgDefault{}
+
+	if len(wgConfs) != 0 {
+		client, e := wgctrl.New()
+		if e != nil {
+			err = &errortypes.ReadError{
+				errors.Wrap(e, "ipsec: Failed to open wg client"),
+			}
+			return
+		}
+		defer client.Close()
+
+		ifaces := []string{}
+		for iface := range wgConfs {
+			ifaces = append(ifaces, iface)
+		}
+		sort.Slice(ifaces, func(i, j int) bool {
+			stateI := wgConfs[ifaces[i]].StateId
+			stateJ := wgConfs[ifaces[j]].StateId
+			if stateI != stateJ {
+				return stateI < stateJ
+			}
+			return ifaces[i] < ifaces[j]
+		})
+
+		for _, iface := range ifaces {
+			conf := wgConfs[iface]
+
+			e = configureWg(client, conf)
+			if e != nil {
+				logrus.WithFields(logrus.Fields{
+					"wg_iface": iface,
+					"error":    e,
+				}).Error("ipsec: Failed to configure wg interface")
+
+				for key, route := range wgRoutes {
+					if route.Iface == iface {
+						newRoutes[key] = route
+					}
+				}
+				for network, def := range wgDefaults {
+					if def.Iface == iface {
+						addWgDefault(newDefaults, network, def)
+					}
+				}
+				if mode := state.WgModes[conf.StateId]; mode != "" {
+					wgModes[conf.StateId] = mode
+				}
+				continue
+			}
+
+			if isUserspaceWg(iface) {
+				wgModes[conf.StateId] = "userspace"
+			} else {
+				wgModes[conf.StateId] = "kernel"
+			}
+
+			if conf.Table == "off" {
+				continue
+			}
+
+			for _, peer := range conf.Peers {
+				for _, network := range peer.AllowedIps {
+					if conf.Table == "" && isDefaultNetwork(network) {
+						addWgDefault(newDefaults, network, &wgDefault{
+							Iface:  iface,
+							FwMark: conf.getFwMark(),
+						})
+						continue
+					}
+
+					route := &wgRoute{
+						Network: network,
+						Iface:   iface,
+						Table:   conf.Table,
+					}
+					newRoutes[route.Key()] = route
+				}
+			}
+		}
+	}
+
+	for key, route := range wgRoutes {
+		newRoute := newRoutes[key]
+		if newRoute == nil || newRoute.Iface != route.Iface {
+			utils.ExecSilent("", "ip", route.args("del")...)
+		}
+	}
+
+	for _, route := range newRoutes {
+		err = utils.Exec("", "ip", route.args("replace")...)
+		if err != nil {
+			return
+		}
+	}
+	wgRoutes = newRoutes
+	state.WgModes = wgModes
+
+	for _, network := range wgDefaultNets {
+		def := newDefaults[network]
+		if def == nil {
+			if curDef := wgDefaults[network]; curDef != nil {
+				clearWgDefault(network, curDef)
+				delete(wgDefaults, network)
+			}
+			continue
+		}
+
+		err = setWgDefault(network, def)
+		if err != nil {
+			return
+		}
+		wgDefaults[network] = def
+	}
+
+	for ifaceInf := range curIfaces.Iter() {
+		iface := ifaceInf.(string)
+		if _, ok := wgConfs[iface]; ok {
+			continue
+		}
+
+		removeWgIface(iface)
+
+		logrus.WithFields(logrus.Fields{
+			"wg_iface": iface,
+		}).Info("ipsec: Removed wg interface")
+	}
+
+	return
+}
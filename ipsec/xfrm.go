@@ -0,0 +1,190 @@
+package ipsec
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/state"
+	"github.com/pritunl/pritunl-link/utils"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	xfrmRoutes     = map[string]string{}
+	xfrmRoutesLock = sync.Mutex{}
+)
+
+func isRouteBased(stat *state.State) bool {
+	return config.Config.RouteBased &&
+		(stat.Protocol == "" || stat.Protocol == "ipsec") &&
+		stat.Type != state.DirectClient && stat.Type != state.DirectServer
+}
+
+func getLinkIface(stat *state.State, link *state.Link) string {
+	if stat.Protocol == "wg" {
+		return GetWgIface(stat.Id)
+	}
+	if isRouteBased(stat) {
+		return GetXfrmIface(stat.Id, link.Id)
+	}
+	return ""
+}
+
+func getXfrmSelectors(subnets []string) string {
+	has4 := false
+	has6 := false
+	for _, subnet := range subnets {
+		if strings.Contains(subnet, ":") {
+			has6 = true
+		} else {
+			has4 = true
+		}
+	}
+
+	selectors := []string{}
+	if has4 || !has6 {
+		selectors = append(selectors, "0.0.0.0/0")
+	}
+	if has6 {
+		selectors = append(selectors, "::/0")
+	}
+
+	return strings.Join(selectors, ",")
+}
+
+func getXfrmIfaces() (ifaces set.Set, err error) {
+	ifaces = set.NewSet()
+
+	output, err := utils.ExecOutput("", "ip", "-o", "link", "show",
+		"type", "xfrm")
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		iface := strings.TrimSuffix(fields[1], ":")
+		iface = strings.SplitN(iface, "@", 2)[0]
+		if strings.HasPrefix(iface, "xfp") {
+			ifaces.Add(iface)
+		}
+	}
+
+	return
+}
+
+func setXfrm(states []*state.State) (err error) {
+	xfrmRoutesLock.Lock()
+	defer xfrmRoutesLock.Unlock()
+
+	curIfaces, err := getXfrmIfaces()
+	if err != nil {
+		return
+	}
+
+	defaultIface := state.GetDefaultInterface()
+	newIfaces := set.NewSet()
+	newRoutes := map[string]string{}
+
+	for _, stat := range states {
+		if !isRouteBased(stat) {
+			continue
+		}
+
+		for _, link := range stat.Links {
+			iface := GetXfrmIface(stat.Id, link.Id)
+			newIfaces.Add(iface)
+
+			if !curIfaces.Contains(iface) {
+				args := []string{
+					"link", "add", iface, "type", "xfrm",
+				}
+				if defaultIface != "" {
+					args = append(args, "dev", defaultIface)
+				}
+				args = append(args, "if_id",
+					fmt.Sprintf("%d", GetXfrmIfId(stat.Id, link.Id)))
+
+				err = utils.Exec("", "ip", args...)
+				if err != nil {
+					return
+				}
+
+				logrus.WithFields(logrus.Fields{
+					"interface": iface,
+					"link_id":   link.Id,
+				}).Info("ipsec: Created xfrm interface")
+			}
+
+			err = utils.Exec("", "ip", "link", "set", iface, "up")
+			if err != nil {
+				return
+			}
+
+			if mtu := getLinkMtu(stat, link); mtu != 0 {
+				err = utils.Exec("", "ip", "link", "set", iface,
+					"mtu", fmt.Sprintf("%d", mtu))
+				if err != nil {
+					return
+				}
+			}
+
+			for _, network := range link.RightSubnets {
+				newRoutes[network] = iface
+			}
+		}
+	}
+
+	for network, iface := range xfrmRoutes {
+		if newRoutes[network] != iface {
+			utils.ExecSilent("", "ip", "route", "del", network, "dev", iface)
+		}
+	}
+
+	for network, iface := range newRoutes {
+		err = utils.Exec("", "ip", "route", "replace", network,
+			"dev", iface)
+		if err != nil {
+			return
+		}
+	}
+	xfrmRoutes = newRoutes
+
+	for ifaceInf := range curIfaces.Iter() {
+		iface := ifaceInf.(string)
+		if newIfaces.Contains(iface) {
+			continue
+		}
+
+		utils.ExecSilent("", "ip", "link", "del", iface)
+
+		logrus.WithFields(logrus.Fields{
+			"interface": iface,
+		}).Info("ipsec: Removed xfrm interface")
+	}
+
+	return
+}
+
+func ClearXfrm() {
+	xfrmRoutesLock.Lock()
+	defer xfrmRoutesLock.Unlock()
+
+	ifaces, err := getXfrmIfaces()
+	if err != nil {
+		return
+	}
+
+	for ifaceInf := range ifaces.Iter() {
+		utils.ExecSilent("", "ip", "link", "del", ifaceInf.(string))
+	}
+
+	xfrmRoutes = map[string]string{}
+}
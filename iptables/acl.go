@@ -0,0 +1,168 @@
+package iptables
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pritunl/pritunl-link/utils"
+)
+
+var (
+	aclCommentReg = regexp.MustCompile(
+		`/\* pritunl-link-acl-(\S+) \*/`)
+	aclNftReg = regexp.MustCompile(
+		`counter packets ([0-9]+) bytes ([0-9]+).*` +
+			`comment "pritunl-link-acl-(.+)-[0-9a-f]{8}"`)
+)
+
+type AclRule struct {
+	Id          string
+	Action      string
+	Interface   string
+	Source      string
+	Destination string
+	Protocol    string
+	Port        string
+}
+
+type AclCounter struct {
+	Packets uint64 `json:"packets"`
+	Bytes   uint64 `json:"bytes"`
+}
+
+func (a *AclRule) rule() (rule []string) {
+	rule = []string{"FORWARD", "1"}
+
+	if a.Interface != "" {
+		rule = append(rule, "-i", a.Interface)
+	} else {
+		rule = append(rule,
+			"-m", "policy",
+			"--dir", "in",
+			"--pol", "ipsec",
+		)
+	}
+
+	rule = append(rule, "-s", a.Source)
+	if a.Destination != "" {
+		rule = append(rule, "-d", a.Destination)
+	}
+
+	if a.Protocol != "" {
+		rule = append(rule, "-p", a.Protocol)
+		if a.Port != "" {
+			rule = append(rule, "-m", a.Protocol, "--dport", a.Port)
+		}
+	}
+
+	target := "DROP"
+	if a.Action == "allow" {
+		target = "ACCEPT"
+	}
+
+	rule = append(rule,
+		"-j", target,
+		"-m", "comment",
+		"--comment", "pritunl-link-acl-"+a.Id,
+	)
+
+	return
+}
+
+func SetAcls(acls []*AclRule) (err error) {
+	for i := len(acls) - 1; i >= 0; i-- {
+		acl := acls[i]
+
+		err = insertRule6(strings.Contains(acl.Source, ":"),
+			"filter", acl.rule()...)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func GetAclCounters() (counters map[string]*AclCounter, err error) {
+	counters = map[string]*AclCounter{}
+
+	if Nftables() {
+		err = nftInit()
+		if err != nil {
+			return
+		}
+
+		output, e := utils.ExecOutput("", "nft", "list", "chain",
+			"inet", "pritunl_link", "forward_filter")
+		if e != nil {
+			err = e
+			return
+		}
+
+		for _, line := range strings.Split(output, "\n") {
+			match := aclNftReg.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+
+			addAclCounter(counters, match[3], match[1], match[2])
+		}
+
+		return
+	}
+
+	for _, iptablesExec := range getIptablesExecs() {
+		output, e := utils.ExecOutput("", iptablesExec,
+			"-t", "filter", "-L", "FORWARD", "-v", "-x", "-n")
+		if e != nil {
+			err = e
+			return
+		}
+
+		for _, line := range strings.Split(output, "\n") {
+			match := aclCommentReg.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+
+			addAclCounter(counters, match[1], fields[0], fields[1])
+		}
+	}
+
+	return
+}
+
+func addAclCounter(counters map[string]*AclCounter,
+	id, packets, bytes string) {
+
+	counter := counters[id]
+	if counter == nil {
+		counter = &AclCounter{}
+		counters[id] = counter
+	}
+
+	n, _ := strconv.ParseUint(packets, 10, 64)
+	counter.Packets += n
+	n, _ = strconv.ParseUint(bytes, 10, 64)
+	counter.Bytes += n
+}
+
+func ClearAcls() (err error) {
+	err = clearIpTables("--comment pritunl-link-acl", false)
+	if err != nil {
+		return
+	}
+
+	err = clearIpTables("--comment pritunl-link-acl", true)
+	if err != nil {
+		return
+	}
+
+	return
+}
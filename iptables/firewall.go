@@ -1,11 +1,12 @@
 package iptables
 
 import (
+	"net"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/dropbox/godropbox/container/set"
-	"github.com/pritunl/pritunl-link/utils"
 )
 
 var (
@@ -15,132 +16,124 @@ var (
 	iptablesLock = sync.Mutex{}
 )
 
+func swapSets(hostsSet, portsSet set.Set) (err error) {
+	hosts4 := []string{}
+	hosts6 := []string{}
+	for hostInf := range hostsSet.Iter() {
+		host := hostInf.(string)
+		if strings.Contains(host, ":") {
+			hosts6 = append(hosts6, host)
+		} else {
+			hosts4 = append(hosts4, host)
+		}
+	}
+
+	wgPorts := []string{}
+	for portInf := range portsSet.Iter() {
+		wgPorts = append(wgPorts, strconv.Itoa(portInf.(int)))
+	}
+
+	if Nftables() {
+		err = nftSwapSets(hosts4, hosts6, wgPorts)
+		if err != nil {
+			return
+		}
+		return
+	}
+
+	err = SwapIpset(getHostSet(false), hosts4)
+	if err != nil {
+		return
+	}
+	err = SwapIpset(getHostSet(true), hosts6)
+	if err != nil {
+		return
+	}
+	err = SwapIpset(getPortSet("wgp", false), wgPorts)
+	if err != nil {
+		return
+	}
+	err = SwapIpset(getPortSet("wgp", true), wgPorts)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
 func SetHosts(hosts []string, ports []int) (err error) {
 	iptablesLock.Lock()
 	defer iptablesLock.Unlock()
 
 	newHostsSet := set.NewSet()
 	for _, host := range hosts {
-		if host == "" {
+		ip := net.ParseIP(strings.Trim(host, "[]"))
+		if ip == nil {
 			continue
 		}
-		newHostsSet.Add(host)
+		newHostsSet.Add(ip.String())
 	}
 
-	curHostsSet := curHosts.Copy()
-
-	removeHosts := curHostsSet.Copy()
-	removeHosts.Subtract(newHostsSet)
-
-	addHosts := newHostsSet.Copy()
-	addHosts.Subtract(curHostsSet)
-
-	curPorts := curWgPorts.Copy()
-
 	newPorts := set.NewSet()
 	for _, port := range ports {
 		newPorts.Add(port)
 	}
 
-	addPorts := newPorts.Copy()
-	addPorts.Subtract(curPorts)
-
-	delPorts := curPorts.Copy()
-	delPorts.Subtract(newPorts)
-
-	if removeHosts.Len() == 0 && addHosts.Len() == 0 &&
-		addPorts.Len() == 0 && delPorts.Len() == 0 {
+	if !initialize && curHosts.IsEqual(newHostsSet) &&
+		curWgPorts.IsEqual(newPorts) {
 
 		return
 	}
 
-	InitWgIpset()
+	err = InitIpsets()
+	if err != nil {
+		return
+	}
 
 	if initialize {
-		ClearWgIpset()
+		ClearIpsets()
 		ClearAcceptIpTables()
-		initialize = false
-	}
-
-	for hostInf := range removeHosts.Iter() {
-		host := hostInf.(string)
-
-		err = DisallowPort(host, "500", "udp")
-		if err != nil {
-			return
-		}
-		err = DisallowPort(host, "4500", "udp")
-		if err != nil {
-			return
-		}
-		err = DisallowPort(host, "9790", "tcp")
-		if err != nil {
-			return
-		}
-		err = DisallowPortSet(host, "wgp", "udp")
-		if err != nil {
-			return
-		}
 	}
 
-	for hostInf := range addHosts.Iter() {
-		host := hostInf.(string)
-
-		err = AllowPort(host, "500", "udp")
-		if err != nil {
-			return
-		}
-		err = AllowPort(host, "4500", "udp")
-		if err != nil {
-			return
-		}
-		err = AllowPort(host, "9790", "tcp")
-		if err != nil {
-			return
-		}
-		err = AllowPortSet(host, "wgp", "udp")
-		if err != nil {
-			return
-		}
-	}
-
-	err = DropPort("500", "udp")
-	if err != nil {
-		return
-	}
-	err = DropPort("4500", "udp")
-	if err != nil {
-		return
-	}
-	err = DropPort("9790", "tcp")
-	if err != nil {
-		return
-	}
-	err = DropPortSet("wgp", "udp")
+	err = swapSets(newHostsSet, newPorts)
 	if err != nil {
 		return
 	}
 
-	curHosts = newHostsSet
-
-	for addPortInf := range addPorts.Iter() {
-		addPort := addPortInf.(int)
-
-		err = utils.Exec("", "ipset", "add", "wgp", strconv.Itoa(addPort))
-		if err != nil {
-			return
+	if initialize {
+		for _, ipv6 := range []bool{false, true} {
+			if ipv6 && !hasIp6tables() {
+				continue
+			}
+			hostSet := getHostSet(ipv6)
+
+			err = AllowPort(hostSet, "500", "udp", ipv6)
+			if err != nil {
+				return
+			}
+			err = AllowPort(hostSet, "4500", "udp", ipv6)
+			if err != nil {
+				return
+			}
+			err = AllowPort(hostSet, "9790", "tcp", ipv6)
+			if err != nil {
+				return
+			}
+			err = AllowPortSet(hostSet, "wgp", "udp", ipv6)
+			if err != nil {
+				return
+			}
 		}
-	}
-
-	for delPortInf := range delPorts.Iter() {
-		delPort := delPortInf.(int)
 
-		err = utils.Exec("", "ipset", "del", "wgp", strconv.Itoa(delPort))
+		err = InitAcceptIpTables()
 		if err != nil {
 			return
 		}
+
+		initialize = false
 	}
 
+	curHosts = newHostsSet
 	curWgPorts = newPorts
 
 	return
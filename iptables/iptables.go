@@ -1,12 +1,66 @@
 package iptables
 
 import (
+	"fmt"
+	"os/exec"
 	"strings"
 
+	"github.com/dropbox/godropbox/container/set"
 	"github.com/pritunl/pritunl-link/utils"
 )
 
+var (
+	ipsetNames = []string{"plhosts", "plhosts6", "wgp", "wgp6"}
+	ipsetTypes = map[string]string{
+		"plhosts":  "hash:ip family inet",
+		"plhosts6": "hash:ip family inet6",
+		"wgp":      "bitmap:port range 0-65535",
+		"wgp6":     "bitmap:port range 0-65535",
+	}
+)
+
+func getHostSet(ipv6 bool) string {
+	if ipv6 {
+		return "plhosts6"
+	}
+	return "plhosts"
+}
+
+func getPortSet(portSet string, ipv6 bool) string {
+	if ipv6 {
+		return portSet + "6"
+	}
+	return portSet
+}
+
+func hasIp6tables() bool {
+	if Nftables() {
+		return true
+	}
+
+	_, err := exec.LookPath("ip6tables")
+	return err == nil
+}
+
+func getIptablesExecs() (execs []string) {
+	execs = []string{"iptables"}
+	if hasIp6tables() {
+		execs = append(execs, "ip6tables")
+	}
+	return
+}
+
 func clearIpTables(match string, ipv6 bool) (err error) {
+	trackedRulesLock.Lock()
+	defer trackedRulesLock.Unlock()
+
+	untrackRules(strings.TrimPrefix(match, "--comment "), ipv6)
+
+	if Nftables() {
+		err = nftClearRules(strings.TrimPrefix(match, "--comment "))
+		return
+	}
+
 	var iptablesExec string
 	if ipv6 {
 		iptablesExec = "ip6tables"
@@ -50,10 +104,50 @@ func clearIpTables(match string, ipv6 bool) (err error) {
 }
 
 func ClearIpTables() (err error) {
-	return clearIpTables("--comment pritunl-link-direct", false)
+	err = clearIpTables("--comment pritunl-link-direct", false)
+	if err != nil {
+		return
+	}
+
+	err = ClearNetmap()
+	if err != nil {
+		return
+	}
+
+	err = ClearMasquerade()
+	if err != nil {
+		return
+	}
+
+	err = ClearAcls()
+	if err != nil {
+		return
+	}
+
+	err = ClearMss()
+	if err != nil {
+		return
+	}
+
+	err = ClearShapeMarks()
+	if err != nil {
+		return
+	}
+
+	return
 }
 
 func UpsertRule(table string, rule ...string) (err error) {
+	if Nftables() {
+		err = nftUpsertRule(false, table, rule)
+		if err != nil {
+			return
+		}
+
+		trackRule(false, table, rule)
+		return
+	}
+
 	args := []string{"-t", table, "-C"}
 	args = append(args, rule...)
 
@@ -68,34 +162,70 @@ func UpsertRule(table string, rule ...string) (err error) {
 		}
 	}
 
+	trackRule(false, table, rule)
+
+	return
+}
+
+func upsertRule6(ipv6 bool, table string, rule ...string) (err error) {
+	if Nftables() {
+		err = nftUpsertRule(ipv6, table, rule)
+		if err != nil {
+			return
+		}
+
+		trackRule(ipv6, table, rule)
+		return
+	}
+
+	if !ipv6 {
+		err = UpsertRule(table, rule...)
+		return
+	}
+
+	args := []string{"-t", table, "-C"}
+	args = append(args, rule...)
+
+	e := utils.ExecSilent("", "ip6tables", args...)
+	if e != nil {
+		args = []string{"-t", table, "-A"}
+		args = append(args, rule...)
+
+		err = utils.Exec("", "ip6tables", args...)
+		if err != nil {
+			return
+		}
+	}
+
+	trackRule(true, table, rule)
+
 	return
 }
 
-func AllowPort(source, port, proto string) (err error) {
+func insertRule6(ipv6 bool, table string, rule ...string) (err error) {
+	if Nftables() {
+		err = nftUpsertRule(ipv6, table, rule)
+		if err != nil {
+			return
+		}
+
+		trackRule(ipv6, table, rule)
+		return
+	}
+
 	var iptablesExec string
-	if strings.Contains(source, ":") {
+	if ipv6 {
 		iptablesExec = "ip6tables"
 	} else {
 		iptablesExec = "iptables"
 	}
 
-	rule := []string{
-		"INPUT", "1",
-		"-p", proto,
-		"-m", proto,
-		"--dport", port,
-		"-s", source,
-		"-j", "ACCEPT",
-		"-m", "comment",
-		"--comment", "pritunl-link-accept",
-	}
-
-	args := []string{"-C"}
-	args = append(args, rule...)
+	args := []string{"-t", table, "-C", rule[0]}
+	args = append(args, rule[2:]...)
 
 	e := utils.ExecSilent("", iptablesExec, args...)
 	if e != nil {
-		args = []string{"-I"}
+		args = []string{"-t", table, "-I"}
 		args = append(args, rule...)
 
 		err = utils.Exec("", iptablesExec, args...)
@@ -104,34 +234,50 @@ func AllowPort(source, port, proto string) (err error) {
 		}
 	}
 
+	trackRule(ipv6, table, rule)
+
 	return
 }
 
-func DisallowPort(source, port, proto string) (err error) {
+func AllowPort(hostSet, port, proto string, ipv6 bool) (err error) {
+	if Nftables() {
+		rule := nftPortRule(port, proto, nftHostsSet(ipv6),
+			"ACCEPT", "pritunl-link-accept")
+
+		err = nftUpsertRule(ipv6, "filter", rule)
+		if err != nil {
+			return
+		}
+
+		trackRule(ipv6, "filter", rule)
+		return
+	}
+
 	var iptablesExec string
-	if strings.Contains(source, ":") {
+	if ipv6 {
 		iptablesExec = "ip6tables"
 	} else {
 		iptablesExec = "iptables"
 	}
 
 	rule := []string{
-		"INPUT",
+		"INPUT", "1",
 		"-p", proto,
 		"-m", proto,
 		"--dport", port,
-		"-s", source,
+		"-m", "set",
+		"--match-set", hostSet, "src",
 		"-j", "ACCEPT",
 		"-m", "comment",
 		"--comment", "pritunl-link-accept",
 	}
 
-	args := []string{"-C"}
-	args = append(args, rule...)
+	args := []string{"-C", rule[0]}
+	args = append(args, rule[2:]...)
 
 	e := utils.ExecSilent("", iptablesExec, args...)
-	if e == nil {
-		args = []string{"-D"}
+	if e != nil {
+		args = []string{"-I"}
 		args = append(args, rule...)
 
 		err = utils.Exec("", iptablesExec, args...)
@@ -140,10 +286,24 @@ func DisallowPort(source, port, proto string) (err error) {
 		}
 	}
 
+	trackRule(ipv6, "filter", rule)
+
 	return
 }
 
 func DropPort(port, proto string) (err error) {
+	if Nftables() {
+		rule := nftPortRule(port, proto, "", "DROP", "pritunl-link-drop")
+
+		err = nftUpsertRule(false, "filter", rule)
+		if err != nil {
+			return
+		}
+
+		trackRule(false, "filter", rule)
+		return
+	}
+
 	rule := []string{
 		"INPUT",
 		"-p", proto,
@@ -154,7 +314,7 @@ func DropPort(port, proto string) (err error) {
 		"--comment", "pritunl-link-drop",
 	}
 
-	for _, iptablesExec := range []string{"iptables", "ip6tables"} {
+	for _, iptablesExec := range getIptablesExecs() {
 		args := []string{"-C"}
 		args = append(args, rule...)
 
@@ -168,14 +328,29 @@ func DropPort(port, proto string) (err error) {
 				return
 			}
 		}
+
+		trackRule(iptablesExec == "ip6tables", "filter", rule)
 	}
 
 	return
 }
 
-func AllowPortSet(source, portSet, proto string) (err error) {
+func AllowPortSet(hostSet, portSet, proto string, ipv6 bool) (err error) {
+	if Nftables() {
+		rule := nftPortRule("@"+portSet, proto, nftHostsSet(ipv6),
+			"ACCEPT", "pritunl-link-accept")
+
+		err = nftUpsertRule(ipv6, "filter", rule)
+		if err != nil {
+			return
+		}
+
+		trackRule(ipv6, "filter", rule)
+		return
+	}
+
 	var iptablesExec string
-	if strings.Contains(source, ":") {
+	if ipv6 {
 		iptablesExec = "ip6tables"
 	} else {
 		iptablesExec = "iptables"
@@ -185,15 +360,16 @@ func AllowPortSet(source, portSet, proto string) (err error) {
 		"INPUT", "1",
 		"-p", proto,
 		"-m", "set",
-		"--match-set", portSet, "dst",
-		"-s", source,
+		"--match-set", getPortSet(portSet, ipv6), "dst",
+		"-m", "set",
+		"--match-set", hostSet, "src",
 		"-j", "ACCEPT",
 		"-m", "comment",
 		"--comment", "pritunl-link-accept",
 	}
 
-	args := []string{"-C"}
-	args = append(args, rule...)
+	args := []string{"-C", rule[0]}
+	args = append(args, rule[2:]...)
 
 	e := utils.ExecSilent("", iptablesExec, args...)
 	if e != nil {
@@ -206,57 +382,36 @@ func AllowPortSet(source, portSet, proto string) (err error) {
 		}
 	}
 
+	trackRule(ipv6, "filter", rule)
+
 	return
 }
 
-func DisallowPortSet(source, portSet, proto string) (err error) {
-	var iptablesExec string
-	if strings.Contains(source, ":") {
-		iptablesExec = "ip6tables"
-	} else {
-		iptablesExec = "iptables"
-	}
-
-	rule := []string{
-		"INPUT",
-		"-p", proto,
-		"-m", "set",
-		"--match-set", portSet, "dst",
-		"-s", source,
-		"-j", "ACCEPT",
-		"-m", "comment",
-		"--comment", "pritunl-link-accept",
-	}
-
-	args := []string{"-C"}
-	args = append(args, rule...)
-
-	e := utils.ExecSilent("", iptablesExec, args...)
-	if e == nil {
-		args = []string{"-D"}
-		args = append(args, rule...)
+func DropPortSet(portSet, proto string) (err error) {
+	if Nftables() {
+		rule := nftPortRule("@"+portSet, proto, "", "DROP", "pritunl-link-drop")
 
-		err = utils.Exec("", iptablesExec, args...)
+		err = nftUpsertRule(false, "filter", rule)
 		if err != nil {
 			return
 		}
-	}
 
-	return
-}
-
-func DropPortSet(portSet, proto string) (err error) {
-	rule := []string{
-		"INPUT",
-		"-p", proto,
-		"-m", "set",
-		"--match-set", portSet, "dst",
-		"-j", "DROP",
-		"-m", "comment",
-		"--comment", "pritunl-link-drop",
+		trackRule(false, "filter", rule)
+		return
 	}
 
-	for _, iptablesExec := range []string{"iptables", "ip6tables"} {
+	for _, iptablesExec := range getIptablesExecs() {
+		rule := []string{
+			"INPUT",
+			"-p", proto,
+			"-m", "set",
+			"--match-set", getPortSet(portSet,
+				iptablesExec == "ip6tables"), "dst",
+			"-j", "DROP",
+			"-m", "comment",
+			"--comment", "pritunl-link-drop",
+		}
+
 		args := []string{"-C"}
 		args = append(args, rule...)
 
@@ -270,25 +425,60 @@ func DropPortSet(portSet, proto string) (err error) {
 				return
 			}
 		}
+
+		trackRule(iptablesExec == "ip6tables", "filter", rule)
 	}
 
 	return
 }
 
-func InitWgIpset() (err error) {
+func InitIpsets() (err error) {
+	if Nftables() {
+		err = nftInit()
+		return
+	}
+
 	output, err := utils.ExecOutput("", "ipset", "list", "-n")
 	if err != nil {
 		return
 	}
 
+	sets := set.NewSet()
 	for _, item := range strings.Split(output, "\n") {
-		if strings.TrimSpace(item) == "wgp" {
+		sets.Add(strings.TrimSpace(item))
+	}
+
+	for _, name := range ipsetNames {
+		if sets.Contains(name) {
+			continue
+		}
+
+		args := []string{"create", name}
+		args = append(args, strings.Fields(ipsetTypes[name])...)
+
+		err = utils.Exec("", "ipset", args...)
+		if err != nil {
 			return
 		}
 	}
 
-	err = utils.Exec("", "ipset", "create", "wgp",
-		"bitmap:port", "range", "0-65535")
+	return
+}
+
+func SwapIpset(name string, members []string) (err error) {
+	tmpName := name + "-tmp"
+	setType := ipsetTypes[name]
+
+	script := fmt.Sprintf("create %s %s\n", name, setType)
+	script += fmt.Sprintf("create %s %s\n", tmpName, setType)
+	script += fmt.Sprintf("flush %s\n", tmpName)
+	for _, member := range members {
+		script += fmt.Sprintf("add %s %s\n", tmpName, member)
+	}
+	script += fmt.Sprintf("swap %s %s\n", tmpName, name)
+	script += fmt.Sprintf("destroy %s\n", tmpName)
+
+	err = utils.ExecInput("", script, "ipset", "-exist", "restore")
 	if err != nil {
 		return
 	}
@@ -297,7 +487,7 @@ func InitWgIpset() (err error) {
 }
 
 func InitAcceptIpTables() (err error) {
-	err = InitWgIpset()
+	err = InitIpsets()
 	if err != nil {
 		return
 	}
@@ -321,25 +511,51 @@ func InitAcceptIpTables() (err error) {
 	return
 }
 
-func ClearWgIpset() (err error) {
-	err = utils.Exec("", "ipset", "flush", "wgp")
-	if err != nil {
+func ClearIpsets() (err error) {
+	if Nftables() {
+		err = nftFlushSet("wgp")
 		return
 	}
 
+	for _, name := range ipsetNames {
+		err = utils.Exec("", "ipset", "flush", name)
+		if err != nil {
+			return
+		}
+	}
+
 	return
 }
 
-func RemoveWgIpset() (err error) {
-	err = utils.Exec("", "ipset", "destroy", "wgp")
-	if err != nil {
+func RemoveIpsets() (err error) {
+	if Nftables() {
+		err = nftFlushSet("wgp")
 		return
 	}
 
+	for _, name := range ipsetNames {
+		e := utils.Exec("", "ipset", "destroy", name)
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+
 	return
 }
 
 func ClearAcceptIpTables() (err error) {
+	if Nftables() {
+		err = nftFlushSet("hosts4")
+		if err != nil {
+			return
+		}
+
+		err = nftFlushSet("hosts6")
+		if err != nil {
+			return
+		}
+	}
+
 	err = clearIpTables("--comment pritunl-link-accept", false)
 	if err != nil {
 		return
@@ -368,6 +584,13 @@ func ClearDropIpTables() (err error) {
 }
 
 func DeleteRule(table string, rule ...string) {
+	untrackRule(false, table, rule)
+
+	if Nftables() {
+		nftDeleteRule(false, table, rule)
+		return
+	}
+
 	args := []string{"-t", table, "-D"}
 	args = append(args, rule...)
 	utils.ExecSilent("", "iptables", args...)
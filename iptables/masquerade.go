@@ -0,0 +1,63 @@
+package iptables
+
+import (
+	"strings"
+)
+
+func SetMasquerade(network, address, iface string,
+	reverse bool) (err error) {
+
+	ipv6 := strings.Contains(network, ":")
+
+	rule := []string{
+		"POSTROUTING",
+		"-s", network,
+	}
+	if iface != "" {
+		rule = append(rule, "-o", iface)
+	}
+	rule = append(rule,
+		"-j", "SNAT",
+		"--to-source", address,
+		"-m", "comment",
+		"--comment", "pritunl-link-masquerade",
+	)
+
+	err = upsertRule6(ipv6, "nat", rule...)
+	if err != nil {
+		return
+	}
+
+	if reverse {
+		err = upsertRule6(
+			ipv6,
+			"nat",
+			"POSTROUTING",
+			"!", "-s", address,
+			"-d", network,
+			"-j", "SNAT",
+			"--to-source", address,
+			"-m", "comment",
+			"--comment", "pritunl-link-masquerade",
+		)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func ClearMasquerade() (err error) {
+	err = clearIpTables("--comment pritunl-link-masquerade", false)
+	if err != nil {
+		return
+	}
+
+	err = clearIpTables("--comment pritunl-link-masquerade", true)
+	if err != nil {
+		return
+	}
+
+	return
+}
@@ -0,0 +1,73 @@
+package iptables
+
+import (
+	"strconv"
+	"strings"
+)
+
+func SetMssClamp(iface, network string, mss int) (err error) {
+	ipv6 := strings.Contains(network, ":")
+
+	for _, dir := range []string{"out", "in"} {
+		rule := []string{"FORWARD"}
+
+		if iface != "" {
+			if dir == "out" {
+				rule = append(rule, "-o", iface)
+			} else {
+				rule = append(rule, "-i", iface)
+			}
+		} else {
+			rule = append(rule,
+				"-m", "policy",
+				"--dir", dir,
+				"--pol", "ipsec",
+			)
+		}
+
+		if dir == "out" {
+			rule = append(rule, "-d", network)
+		} else {
+			rule = append(rule, "-s", network)
+		}
+
+		rule = append(rule,
+			"-p", "tcp",
+			"-m", "tcp",
+			"--tcp-flags", "SYN,RST", "SYN",
+			"-j", "TCPMSS",
+		)
+
+		if mss == 0 {
+			rule = append(rule, "--clamp-mss-to-pmtu")
+		} else {
+			rule = append(rule, "--set-mss", strconv.Itoa(mss))
+		}
+
+		rule = append(rule,
+			"-m", "comment",
+			"--comment", "pritunl-link-mss",
+		)
+
+		err = upsertRule6(ipv6, "mangle", rule...)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func ClearMss() (err error) {
+	err = clearIpTables("--comment pritunl-link-mss", false)
+	if err != nil {
+		return
+	}
+
+	err = clearIpTables("--comment pritunl-link-mss", true)
+	if err != nil {
+		return
+	}
+
+	return
+}
@@ -0,0 +1,61 @@
+package iptables
+
+import (
+	"strings"
+)
+
+func SetNetmap(network, mapped string, remotes []string) (err error) {
+	ipv6 := strings.Contains(mapped, ":")
+
+	for _, remote := range remotes {
+		if strings.Contains(remote, ":") != ipv6 {
+			continue
+		}
+
+		err = upsertRule6(
+			ipv6,
+			"nat",
+			"PREROUTING",
+			"-s", remote,
+			"-d", mapped,
+			"-j", "NETMAP",
+			"--to", network,
+			"-m", "comment",
+			"--comment", "pritunl-link-netmap",
+		)
+		if err != nil {
+			return
+		}
+
+		err = upsertRule6(
+			ipv6,
+			"nat",
+			"POSTROUTING",
+			"-s", network,
+			"-d", remote,
+			"-j", "NETMAP",
+			"--to", mapped,
+			"-m", "comment",
+			"--comment", "pritunl-link-netmap",
+		)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func ClearNetmap() (err error) {
+	err = clearIpTables("--comment pritunl-link-netmap", false)
+	if err != nil {
+		return
+	}
+
+	err = clearIpTables("--comment pritunl-link-netmap", true)
+	if err != nil {
+		return
+	}
+
+	return
+}
@@ -0,0 +1,503 @@
+package iptables
+
+import (
+	"crypto/md5"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/errortypes"
+	"github.com/pritunl/pritunl-link/utils"
+)
+
+const nftTable = "inet pritunl_link"
+
+const nftTableConf = `add table inet pritunl_link
+add set inet pritunl_link hosts4 { type ipv4_addr; }
+add set inet pritunl_link hosts6 { type ipv6_addr; }
+add set inet pritunl_link wgp { type inet_service; }
+add chain inet pritunl_link input { type filter hook input priority filter; policy accept; }
+add chain inet pritunl_link forward_mangle { type filter hook forward priority mangle; policy accept; }
+add chain inet pritunl_link forward_filter { type filter hook forward priority filter; policy accept; }
+add chain inet pritunl_link forward { type filter hook forward priority mangle; policy accept; }
+flush chain inet pritunl_link forward
+delete chain inet pritunl_link forward
+add chain inet pritunl_link prerouting { type nat hook prerouting priority dstnat; policy accept; }
+add chain inet pritunl_link postrouting { type nat hook postrouting priority srcnat; policy accept; }
+`
+
+var (
+	nftBackend     = false
+	nftDetected    = false
+	nftInitialized = false
+	nftLock        = sync.Mutex{}
+	nftHandleReg   = regexp.MustCompile(`# handle ([0-9]+)$`)
+	nftChains      = map[string]string{
+		"filter INPUT":    "input",
+		"filter FORWARD":  "forward_filter",
+		"mangle FORWARD":  "forward_mangle",
+		"nat PREROUTING":  "prerouting",
+		"nat POSTROUTING": "postrouting",
+	}
+)
+
+func Nftables() bool {
+	nftLock.Lock()
+	defer nftLock.Unlock()
+
+	if nftDetected {
+		return nftBackend
+	}
+
+	switch config.Config.FirewallBackend {
+	case "nftables":
+		nftBackend = true
+		break
+	case "iptables":
+		nftBackend = false
+		break
+	default:
+		_, legacyErr := exec.LookPath("iptables-legacy")
+		_, nftErr := exec.LookPath("nft")
+		nftBackend = legacyErr != nil && nftErr == nil
+	}
+	nftDetected = true
+
+	return nftBackend
+}
+
+func nftExec(script string) (err error) {
+	err = utils.ExecInput("", script, "nft", "-f", "-")
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func nftInit() (err error) {
+	nftLock.Lock()
+	defer nftLock.Unlock()
+
+	if nftInitialized {
+		return
+	}
+
+	err = nftExec(nftTableConf)
+	if err != nil {
+		return
+	}
+	nftInitialized = true
+
+	return
+}
+
+func nftTranslate(ipv6 bool, table string, rule []string) (
+	chain, expr string, insert bool, err error) {
+
+	if len(rule) == 0 {
+		err = &errortypes.ParseError{
+			errors.New("iptables: Empty nftables rule"),
+		}
+		return
+	}
+
+	chain = nftChains[table+" "+rule[0]]
+	if chain == "" {
+		err = &errortypes.ParseError{
+			errors.Newf("iptables: Unknown nftables chain '%s %s'",
+				table, rule[0]),
+		}
+		return
+	}
+	rule = rule[1:]
+
+	if len(rule) > 0 && rule[0] == "1" {
+		insert = true
+		rule = rule[1:]
+	}
+
+	family := "ip"
+	if ipv6 {
+		family = "ip6"
+	}
+
+	matches := []string{}
+	target := ""
+	comment := ""
+	proto := ""
+	source := ""
+	dest := ""
+	policyDir := ""
+	negate := false
+
+	for i := 0; i < len(rule); i++ {
+		arg := rule[i]
+		val := ""
+		if i+1 < len(rule) {
+			val = rule[i+1]
+		}
+
+		op := ""
+		if negate {
+			op = "!= "
+			negate = false
+		}
+
+		switch arg {
+		case "!":
+			negate = true
+			break
+		case "-s":
+			source = val
+			matches = append(matches,
+				fmt.Sprintf("%s saddr %s%s", family, op, val))
+			i += 1
+			break
+		case "-d":
+			dest = val
+			matches = append(matches,
+				fmt.Sprintf("%s daddr %s%s", family, op, val))
+			i += 1
+			break
+		case "-i":
+			matches = append(matches,
+				fmt.Sprintf("iifname %s\"%s\"", op, val))
+			i += 1
+			break
+		case "-o":
+			matches = append(matches,
+				fmt.Sprintf("oifname %s\"%s\"", op, val))
+			i += 1
+			break
+		case "-p":
+			proto = val
+			i += 1
+			break
+		case "-m":
+			i += 1
+			break
+		case "--dport":
+			matches = append(matches, fmt.Sprintf("%s dport %s%s",
+				proto, op, strings.Replace(val, ":", "-", 1)))
+			proto = ""
+			i += 1
+			break
+		case "--match-set":
+			matches = append(matches,
+				fmt.Sprintf("%s dport %s@%s", proto, op, val))
+			proto = ""
+			i += 2
+			break
+		case "--tcp-flags":
+			if i+2 < len(rule) {
+				matches = append(matches, fmt.Sprintf(
+					"tcp flags & (%s) == %s",
+					strings.ToLower(strings.Replace(val, ",", " | ", -1)),
+					strings.ToLower(rule[i+2])))
+			}
+			proto = ""
+			i += 2
+			break
+		case "--dir":
+			policyDir = val
+			i += 1
+			break
+		case "--pol":
+			policy := "missing"
+			if val == "ipsec" {
+				policy = "exists"
+			}
+			if policyDir == "out" {
+				matches = append(matches, "rt ipsec "+policy)
+			} else {
+				matches = append(matches, "meta secpath "+policy)
+			}
+			i += 1
+			break
+		case "--comment":
+			comment = val
+			i += 1
+			break
+		case "-j":
+			switch val {
+			case "ACCEPT":
+				target = "accept"
+				break
+			case "DROP":
+				target = "drop"
+				break
+			case "MASQUERADE":
+				target = "masquerade"
+				break
+			}
+			i += 1
+			break
+		case "--to-destination":
+			target = fmt.Sprintf("dnat %s to %s", family, val)
+			i += 1
+			break
+		case "--to-source":
+			target = fmt.Sprintf("snat %s to %s", family, val)
+			i += 1
+			break
+		case "--to":
+			if chain == "prerouting" {
+				target = fmt.Sprintf(
+					"dnat %s prefix to %s daddr map { %s : %s }",
+					family, family, dest, val)
+			} else {
+				target = fmt.Sprintf(
+					"snat %s prefix to %s saddr map { %s : %s }",
+					family, family, source, val)
+			}
+			i += 1
+			break
+		case "--set-mark":
+			target = fmt.Sprintf("meta mark set %s", val)
+			i += 1
+			break
+		case "--clamp-mss-to-pmtu":
+			target = "tcp option maxseg size set rt mtu"
+			break
+		case "--set-mss":
+			target = fmt.Sprintf("tcp option maxseg size set %s", val)
+			i += 1
+			break
+		default:
+			err = &errortypes.ParseError{
+				errors.Newf("iptables: Unknown nftables rule arg '%s'",
+					arg),
+			}
+			return
+		}
+	}
+
+	if proto != "" {
+		matches = append(matches, "meta l4proto "+proto)
+	}
+
+	if target == "" {
+		err = &errortypes.ParseError{
+			errors.New("iptables: Missing nftables rule target"),
+		}
+		return
+	}
+
+	expr = strings.Join(append(matches, "counter", target), " ")
+
+	if comment != "" {
+		hash := md5.Sum([]byte(chain + expr))
+		expr += fmt.Sprintf(" comment \"%s-%x\"", comment, hash[:4])
+	}
+
+	return
+}
+
+func nftGetHandles(chain, match string) (handles []string, err error) {
+	output, err := utils.ExecOutput("", "nft", "-a", "list", "chain",
+		"inet", "pritunl_link", chain)
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.Contains(line, match) {
+			continue
+		}
+
+		handle := nftHandleReg.FindStringSubmatch(line)
+		if handle == nil {
+			continue
+		}
+
+		handles = append(handles, handle[1])
+	}
+
+	return
+}
+
+func nftGetComment(expr string) string {
+	n := strings.LastIndex(expr, " comment ")
+	if n == -1 {
+		return expr
+	}
+	return expr[n+1:]
+}
+
+func nftUpsertRule(ipv6 bool, table string, rule []string) (err error) {
+	err = nftInit()
+	if err != nil {
+		return
+	}
+
+	chain, expr, insert, err := nftTranslate(ipv6, table, rule)
+	if err != nil {
+		return
+	}
+
+	handles, err := nftGetHandles(chain, nftGetComment(expr))
+	if err != nil {
+		return
+	}
+
+	if len(handles) > 0 {
+		return
+	}
+
+	cmd := "add"
+	if insert {
+		cmd = "insert"
+	}
+
+	err = nftExec(fmt.Sprintf("%s rule %s %s %s\n",
+		cmd, nftTable, chain, expr))
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func nftDeleteRule(ipv6 bool, table string, rule []string) (err error) {
+	err = nftInit()
+	if err != nil {
+		return
+	}
+
+	chain, expr, _, err := nftTranslate(ipv6, table, rule)
+	if err != nil {
+		return
+	}
+
+	handles, err := nftGetHandles(chain, nftGetComment(expr))
+	if err != nil {
+		return
+	}
+
+	script := ""
+	for _, handle := range handles {
+		script += fmt.Sprintf("delete rule %s %s handle %s\n",
+			nftTable, chain, handle)
+	}
+
+	if script != "" {
+		err = nftExec(script)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func nftClearRules(comment string) (err error) {
+	err = nftInit()
+	if err != nil {
+		return
+	}
+
+	script := ""
+	for _, chain := range nftChains {
+		handles, e := nftGetHandles(chain, "comment \""+comment+"-")
+		if e != nil {
+			err = e
+			return
+		}
+
+		for _, handle := range handles {
+			script += fmt.Sprintf("delete rule %s %s handle %s\n",
+				nftTable, chain, handle)
+		}
+	}
+
+	if script != "" {
+		err = nftExec(script)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func nftHostsSet(ipv6 bool) string {
+	if ipv6 {
+		return "@hosts6"
+	}
+	return "@hosts4"
+}
+
+func nftSwapSets(hosts4, hosts6, ports []string) (err error) {
+	err = nftInit()
+	if err != nil {
+		return
+	}
+
+	script := ""
+	for name, members := range map[string][]string{
+		"hosts4": hosts4,
+		"hosts6": hosts6,
+		"wgp":    ports,
+	} {
+		script += fmt.Sprintf("flush set %s %s\n", nftTable, name)
+		if len(members) > 0 {
+			script += fmt.Sprintf("add element %s %s { %s }\n",
+				nftTable, name, strings.Join(members, ", "))
+		}
+	}
+
+	err = nftExec(script)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func nftPortRule(port, proto, source, target, comment string) (
+	rule []string) {
+
+	rule = []string{"INPUT"}
+	if target == "ACCEPT" {
+		rule = append(rule, "1")
+	}
+
+	if source != "" {
+		rule = append(rule, "-s", source)
+	}
+
+	rule = append(rule, "-p", proto)
+	if strings.HasPrefix(port, "@") {
+		rule = append(rule, "--match-set", port[1:], "dst")
+	} else {
+		rule = append(rule, "--dport", port)
+	}
+
+	rule = append(rule,
+		"-j", target,
+		"-m", "comment",
+		"--comment", comment,
+	)
+
+	return
+}
+
+func nftFlushSet(name string) (err error) {
+	err = nftInit()
+	if err != nil {
+		return
+	}
+
+	err = nftExec(fmt.Sprintf("flush set %s %s\n", nftTable, name))
+	if err != nil {
+		return
+	}
+
+	return
+}
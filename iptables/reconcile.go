@@ -0,0 +1,231 @@
+package iptables
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/utils"
+	"github.com/sirupsen/logrus"
+)
+
+type trackedRule struct {
+	Ipv6   bool
+	Table  string
+	Index  int
+	Insert bool
+	Rule   []string
+}
+
+var (
+	trackedRules     = map[string]*trackedRule{}
+	trackedRulesLock = sync.Mutex{}
+	trackedRulesNext = 0
+)
+
+func (r *trackedRule) key() string {
+	return fmt.Sprintf("%t %s %s", r.Ipv6, r.Table,
+		strings.Join(r.Rule, " "))
+}
+
+func (r *trackedRule) exec() string {
+	if r.Ipv6 {
+		return "ip6tables"
+	}
+	return "iptables"
+}
+
+func (r *trackedRule) exists() bool {
+	if Nftables() {
+		chain, expr, _, err := nftTranslate(r.Ipv6, r.Table, r.Rule)
+		if err != nil {
+			return false
+		}
+
+		handles, err := nftGetHandles(chain, nftGetComment(expr))
+		if err != nil {
+			return false
+		}
+
+		return len(handles) > 0
+	}
+
+	rule := r.Rule
+	if r.Insert {
+		rule = append([]string{rule[0]}, rule[2:]...)
+	}
+
+	args := []string{"-t", r.Table, "-C"}
+	args = append(args, rule...)
+
+	return utils.ExecSilent("", r.exec(), args...) == nil
+}
+
+func (r *trackedRule) add() (err error) {
+	if Nftables() {
+		err = nftUpsertRule(r.Ipv6, r.Table, r.Rule)
+		return
+	}
+
+	args := []string{"-t", r.Table}
+	if r.Insert {
+		args = append(args, "-I")
+	} else {
+		args = append(args, "-A")
+	}
+	args = append(args, r.Rule...)
+
+	err = utils.Exec("", r.exec(), args...)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func trackRule(ipv6 bool, table string, rule []string) {
+	r := &trackedRule{
+		Ipv6:   ipv6,
+		Table:  table,
+		Insert: len(rule) > 1 && rule[1] == "1",
+		Rule:   rule,
+	}
+
+	trackedRulesLock.Lock()
+	if _, ok := trackedRules[r.key()]; !ok {
+		r.Index = trackedRulesNext
+		trackedRulesNext += 1
+		trackedRules[r.key()] = r
+	}
+	trackedRulesLock.Unlock()
+}
+
+func untrackRule(ipv6 bool, table string, rule []string) {
+	r := &trackedRule{
+		Ipv6:  ipv6,
+		Table: table,
+		Rule:  rule,
+	}
+
+	trackedRulesLock.Lock()
+	delete(trackedRules, r.key())
+	trackedRulesLock.Unlock()
+}
+
+func untrackRules(match string, ipv6 bool) {
+	for key, r := range trackedRules {
+		if !Nftables() && r.Ipv6 != ipv6 {
+			continue
+		}
+
+		if strings.Contains(strings.Join(r.Rule, " "), match) {
+			delete(trackedRules, key)
+		}
+	}
+}
+
+func checkIpsets() (err error) {
+	if !config.Config.Firewall {
+		return
+	}
+
+	missing := false
+
+	if Nftables() {
+		e := utils.ExecSilent("", "nft", "list", "table", "inet",
+			"pritunl_link")
+		if e != nil {
+			missing = true
+
+			nftLock.Lock()
+			nftInitialized = false
+			nftLock.Unlock()
+		}
+	} else if !initialize {
+		output, e := utils.ExecOutput("", "ipset", "list", "-n")
+		if e != nil {
+			err = e
+			return
+		}
+
+		sets := set.NewSet()
+		for _, item := range strings.Split(output, "\n") {
+			sets.Add(strings.TrimSpace(item))
+		}
+
+		for _, name := range ipsetNames {
+			if !sets.Contains(name) {
+				missing = true
+				break
+			}
+		}
+	}
+
+	if !missing || initialize {
+		return
+	}
+
+	logrus.Warn("iptables: Firewall sets missing, reapplying")
+
+	err = InitIpsets()
+	if err != nil {
+		return
+	}
+
+	err = swapSets(curHosts, curWgPorts)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func CheckIpTables() (err error) {
+	iptablesLock.Lock()
+	defer iptablesLock.Unlock()
+
+	err = checkIpsets()
+	if err != nil {
+		return
+	}
+
+	trackedRulesLock.Lock()
+	defer trackedRulesLock.Unlock()
+
+	missing := []*trackedRule{}
+	for _, r := range trackedRules {
+		if r.exists() {
+			continue
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"ipv6":  r.Ipv6,
+			"table": r.Table,
+			"rule":  strings.Join(r.Rule, " "),
+		}).Warn("iptables: Firewall rule missing, reapplying")
+
+		missing = append(missing, r)
+	}
+
+	sort.Slice(missing, func(i, j int) bool {
+		if missing[i].Insert != missing[j].Insert {
+			return missing[i].Insert
+		}
+		if missing[i].Insert {
+			return missing[i].Index > missing[j].Index
+		}
+		return missing[i].Index < missing[j].Index
+	})
+
+	for _, r := range missing {
+		err = r.add()
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
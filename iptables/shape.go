@@ -0,0 +1,43 @@
+package iptables
+
+import (
+	"fmt"
+	"strings"
+)
+
+func SetShapeMark(network string, mark int) (err error) {
+	ipv6 := strings.Contains(network, ":")
+
+	err = upsertRule6(
+		ipv6,
+		"mangle",
+		"FORWARD",
+		"-d", network,
+		"-m", "policy",
+		"--dir", "out",
+		"--pol", "ipsec",
+		"-j", "MARK",
+		"--set-mark", fmt.Sprintf("0x%x", mark),
+		"-m", "comment",
+		"--comment", "pritunl-link-shape",
+	)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func ClearShapeMarks() (err error) {
+	err = clearIpTables("--comment pritunl-link-shape", false)
+	if err != nil {
+		return
+	}
+
+	err = clearIpTables("--comment pritunl-link-shape", true)
+	if err != nil {
+		return
+	}
+
+	return
+}
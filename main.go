@@ -20,6 +20,7 @@ Commands:
   remove                    Remove a Pritunl server URI
   clear                     Clear all configured Pritunl server URIs
   list                      List Pritunl server URIs
+  status                    Show IPsec link status, negotiated algorithms and wg interfaces
   default-interface         Manually set default interface
   default-gateway           Manually set default gateway
   local-address             Manually set local IP address
@@ -30,6 +31,7 @@ Commands:
   direct-ssh-off            Disable direct SSH
   firewall-on               Allow access to ipsec ports only from other pritunl-link hosts
   firewall-off              Do not modify system firewall
+  firewall-backend          Set firewall backend (iptables, nftables or empty for auto)
   verify-on                 Enable HTTPS certificate verification when connecting to Pritunl server
   verify-off                Disable HTTPS certificate verification when connecting to Pritunl server
   host-check-on             Enable link host checking
@@ -40,6 +42,51 @@ Commands:
   advertise-update-off      Disable recurring checks and updates of routing table and port forwarding
   custom-option-add         Add custom ipsec option
   custom-option-clear       Clear custom ipsec options
+  proxy-interface           Set interface for proxy ARP/NDP
+  proxy-network-add         Add remote address or subnet to proxy ARP/NDP
+  proxy-network-remove      Remove remote address or subnet from proxy
+  netmap-add                Add 1:1 NAT from local network to mapped link subnet
+  netmap-remove             Remove 1:1 NAT for mapped link subnet
+  masquerade-on             Masquerade traffic from all link subnets to local address
+  masquerade-off            Disable masquerade for all link subnets
+  masquerade-reverse-on     Also masquerade local traffic into links
+  masquerade-reverse-off    Disable reverse masquerade
+  masquerade-interface      Set LAN interface for masquerade
+  masquerade-network-add    Enable masquerade for a remote link subnet
+  masquerade-network-remove Disable masquerade for a remote link subnet
+  acl-add                   Add or update forwarding acl with id and action (allow, deny)
+  acl-remove                Remove forwarding acl
+  acl-state                 Limit forwarding acl to state id from uri
+  acl-link                  Limit forwarding acl to link id
+  acl-source                Set forwarding acl remote source network
+  acl-destination           Set forwarding acl local destination network
+  acl-protocol              Set forwarding acl protocol (tcp, udp, icmp, ipv6-icmp)
+  acl-port                  Set forwarding acl destination port or range (1000:2000)
+  shape-uplink              Set uplink rate in kbit used for link priority shaping
+  shape-egress              Set egress rate limit in kbit for state id from uri
+  shape-ingress             Set ingress rate limit in kbit for state id from uri
+  shape-priority            Set priority class (0-7, lower is higher) for state id
+  shape-remove              Remove traffic shaping for state id
+  mtu-discovery-on          Probe path MTU to link peers and set tunnel MTU and MSS
+  mtu-discovery-off         Disable path MTU probing
+  mtu-set                   Set tunnel MTU for state id, all links
+  mtu-link-set              Set tunnel MTU for state id and link id
+  mtu-remove                Remove tunnel MTU override for state id [link id]
+  route-based-on            Enable route based IPsec with XFRM interfaces
+  route-based-off           Disable route based IPsec
+  cipher-profile            Set cipher profile compatible, fips, modern or pq
+  cipher-profile-set        Set cipher profile for state id
+  cipher-profile-remove     Remove cipher profile for state id
+  tuning-set                Set IPsec tuning option for state id
+  tuning-link-set           Set IPsec tuning option for state id and link id
+  tuning-remove             Remove IPsec tuning options for state id [link id]
+  wg-tuning-set             Set wg option (mtu, keepalive, fwmark, table, handshake_timeout) for state id
+  wg-tuning-remove          Remove wg tuning options for state id
+  wg-key-rotate             Rotate wg key for state id
+  wg-key-rotation           Set wg key rotation interval in days, 0 to disable
+  wg-key-grace              Set seconds to keep old wg key after rotation
+  wg-key-export             Export encrypted key store to path, passphrase read from stdin
+  wg-key-import             Import encrypted key store from path, passphrase read from stdin
   provider                  Manually set network provider
   oracle-user-ocid          Set Oracle user ocid
   oracle-private-key        Set Oracle base64 private key
@@ -48,6 +95,23 @@ Commands:
   oracle-compartment-ocid   Set Oracle compartment ocid
   oracle-vnic-ocid          Set Oracle vnic ocid
   oracle-private-ip-ocid    Set Oracle private IP ocid
+  openstack-auth-url        Set OpenStack Keystone v3 auth URL
+  openstack-region          Set OpenStack region
+  openstack-domain          Set OpenStack user domain
+  openstack-project-id      Set OpenStack project id
+  openstack-username        Set OpenStack username
+  openstack-password        Set OpenStack password
+  openstack-cred-id         Set OpenStack application credential id
+  openstack-cred-secret     Set OpenStack application credential secret
+  openstack-port-id         Set OpenStack port id if different then instance port
+  openstack-router-id       Set OpenStack router id if different then subnet router
+  alibaba-region            Set Alibaba Cloud region
+  alibaba-vpc-id            Set Alibaba Cloud VPC id
+  alibaba-instance-id       Set Alibaba Cloud instance id
+  alibaba-access-key-id     Set Alibaba Cloud access key id
+  alibaba-access-secret     Set Alibaba Cloud access key secret
+  ibm-api-key               Set IBM Cloud API key
+  ibm-region                Set IBM Cloud region
   unifi-username            Set Unifi username
   unifi-password            Set Unifi password
   unifi-controller          Set URL of Unifi controller
@@ -60,6 +124,11 @@ Commands:
   edge-hostname             Set hostname of EdgeRouter
   edge-port-on              Enable automatic port forwarding on EdgeRouter
   edge-port-off             Disable automatic port forwarding on EdgeRouter
+  vyos-hostname             Set hostname of VyOS router
+  vyos-key                  Set VyOS HTTP API key
+  vyos-interface            Set the VyOS inbound interface for port forwarding
+  vyos-port-on              Enable automatic port forwarding on VyOS
+  vyos-port-off             Disable automatic port forwarding on VyOS
   pritunl-hostname          Set hostname of Pritunl Cloud server
   pritunl-organization      Set Pritunl Cloud organization ID
   pritunl-vpc               Set Pritunl Cloud VPC ID
@@ -67,6 +136,30 @@ Commands:
   pritunl-secret            Set Pritunl Cloud secret
   hetzner-token             Set Hetzner token
   hetzner-network-id        Set Hetzner network id
+  bgp-asn                   Set local BGP autonomous system number
+  bgp-router-id             Set BGP router id
+  bgp-hold-time             Set BGP hold time in seconds
+  bgp-next-hop              Set BGP IPv4 next hop for announced routes
+  bgp-next-hop6             Set BGP IPv6 next hop for announced routes
+  bgp-neighbor-add          Add BGP neighbor with address and asn
+  bgp-neighbor-remove       Remove BGP neighbor
+  bgp-neighbor-port         Set BGP neighbor port
+  bgp-neighbor-med          Set BGP neighbor MED
+  bgp-neighbor-local-pref   Set BGP neighbor local preference
+  bgp-community-add         Add community to BGP neighbor
+  bgp-community-clear       Clear BGP neighbor communities
+  frr-next-hop              Set FRR static route next hop (default local address)
+  frr-next-hop6             Set FRR static route IPv6 next hop (default local address)
+  frr-vrf                   Set FRR vrf for static routes
+  frr-tag                   Set FRR static route tag for redistribution
+  frr-distance              Set FRR static route administrative distance
+  ssh-host-add              Add LAN host to push routes to over SSH
+  ssh-host-remove           Remove LAN host from SSH route push
+  ssh-username              Set SSH username for route push
+  ssh-private-key           Set path of SSH private key for route push
+  ssh-known-hosts           Set path of SSH known hosts file (required)
+  ssh-add-command           Set SSH add route command template
+  ssh-delete-command        Set SSH delete route command template
 `
 
 func Init() {
@@ -120,6 +213,13 @@ func main() {
 			panic(err)
 		}
 		break
+	case "status":
+		Init()
+		err := cmd.Status()
+		if err != nil {
+			panic(err)
+		}
+		break
 	case "default-interface":
 		Init()
 		err := cmd.DefaultInterface(flag.Arg(1))
@@ -190,6 +290,13 @@ func main() {
 			panic(err)
 		}
 		break
+	case "firewall-backend":
+		Init()
+		err := cmd.FirewallBackend(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
 	case "verify-on":
 		Init()
 		err := cmd.VerifyOn()
@@ -260,6 +367,321 @@ func main() {
 			panic(err)
 		}
 		break
+	case "proxy-interface":
+		Init()
+		err := cmd.ProxyInterface(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "proxy-network-add":
+		Init()
+		err := cmd.AddProxyNetwork(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "proxy-network-remove":
+		Init()
+		err := cmd.RemoveProxyNetwork(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "netmap-add":
+		Init()
+		err := cmd.AddNetmap(flag.Arg(1), flag.Arg(2))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "netmap-remove":
+		Init()
+		err := cmd.RemoveNetmap(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "masquerade-on":
+		Init()
+		err := cmd.MasqueradeOn()
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "masquerade-off":
+		Init()
+		err := cmd.MasqueradeOff()
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "masquerade-reverse-on":
+		Init()
+		err := cmd.MasqueradeReverseOn()
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "masquerade-reverse-off":
+		Init()
+		err := cmd.MasqueradeReverseOff()
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "masquerade-interface":
+		Init()
+		err := cmd.MasqueradeInterface(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "masquerade-network-add":
+		Init()
+		err := cmd.AddMasqueradeNetwork(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "masquerade-network-remove":
+		Init()
+		err := cmd.RemoveMasqueradeNetwork(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "acl-add":
+		Init()
+		err := cmd.AclAdd(flag.Arg(1), flag.Arg(2))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "acl-remove":
+		Init()
+		err := cmd.AclRemove(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "acl-state":
+		Init()
+		err := cmd.AclState(flag.Arg(1), flag.Arg(2))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "acl-link":
+		Init()
+		err := cmd.AclLink(flag.Arg(1), flag.Arg(2))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "acl-source":
+		Init()
+		err := cmd.AclSource(flag.Arg(1), flag.Arg(2))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "acl-destination":
+		Init()
+		err := cmd.AclDestination(flag.Arg(1), flag.Arg(2))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "acl-protocol":
+		Init()
+		err := cmd.AclProtocol(flag.Arg(1), flag.Arg(2))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "acl-port":
+		Init()
+		err := cmd.AclPort(flag.Arg(1), flag.Arg(2))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "shape-uplink":
+		Init()
+		err := cmd.ShapeUplink(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "shape-egress":
+		Init()
+		err := cmd.ShapeEgress(flag.Arg(1), flag.Arg(2))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "shape-ingress":
+		Init()
+		err := cmd.ShapeIngress(flag.Arg(1), flag.Arg(2))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "shape-priority":
+		Init()
+		err := cmd.ShapePriority(flag.Arg(1), flag.Arg(2))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "shape-remove":
+		Init()
+		err := cmd.ShapeRemove(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "mtu-discovery-on":
+		Init()
+		err := cmd.MtuDiscoveryOn()
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "mtu-discovery-off":
+		Init()
+		err := cmd.MtuDiscoveryOff()
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "mtu-set":
+		Init()
+		err := cmd.MtuSet(flag.Arg(1), "", flag.Arg(2))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "mtu-link-set":
+		Init()
+		err := cmd.MtuSet(flag.Arg(1), flag.Arg(2), flag.Arg(3))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "mtu-remove":
+		Init()
+		err := cmd.MtuRemove(flag.Arg(1), flag.Arg(2))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "route-based-on":
+		Init()
+		err := cmd.RouteBasedOn()
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "route-based-off":
+		Init()
+		err := cmd.RouteBasedOff()
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "cipher-profile":
+		Init()
+		err := cmd.CipherProfile(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "cipher-profile-set":
+		Init()
+		err := cmd.CipherProfileSet(flag.Arg(1), flag.Arg(2))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "cipher-profile-remove":
+		Init()
+		err := cmd.CipherProfileRemove(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "tuning-set":
+		Init()
+		err := cmd.TuningSet(flag.Arg(1), "", flag.Arg(2), flag.Arg(3))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "tuning-link-set":
+		Init()
+		err := cmd.TuningSet(flag.Arg(1), flag.Arg(2), flag.Arg(3), flag.Arg(4))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "tuning-remove":
+		Init()
+		err := cmd.TuningRemove(flag.Arg(1), flag.Arg(2))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "wg-tuning-set":
+		Init()
+		err := cmd.WgTuningSet(flag.Arg(1), flag.Arg(2), flag.Arg(3))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "wg-tuning-remove":
+		Init()
+		err := cmd.WgTuningRemove(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "wg-key-rotate":
+		Init()
+		err := cmd.WgKeyRotate(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "wg-key-rotation":
+		Init()
+		err := cmd.WgKeyRotation(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "wg-key-grace":
+		Init()
+		err := cmd.WgKeyGrace(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "wg-key-export":
+		Init()
+		err := cmd.WgKeyExport(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "wg-key-import":
+		Init()
+		err := cmd.WgKeyImport(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
 	case "provider":
 		Init()
 		err := cmd.Provider(flag.Arg(1))
@@ -267,6 +689,125 @@ func main() {
 			panic(err)
 		}
 		break
+	case "openstack-auth-url":
+		Init()
+		err := cmd.OpenstackAuthUrl(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "openstack-region":
+		Init()
+		err := cmd.OpenstackRegion(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "openstack-domain":
+		Init()
+		err := cmd.OpenstackDomain(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "openstack-project-id":
+		Init()
+		err := cmd.OpenstackProjectId(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "openstack-username":
+		Init()
+		err := cmd.OpenstackUsername(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "openstack-password":
+		Init()
+		err := cmd.OpenstackPassword(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "openstack-cred-id":
+		Init()
+		err := cmd.OpenstackCredentialId(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "openstack-cred-secret":
+		Init()
+		err := cmd.OpenstackCredentialSecret(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "openstack-port-id":
+		Init()
+		err := cmd.OpenstackPortId(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "openstack-router-id":
+		Init()
+		err := cmd.OpenstackRouterId(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "alibaba-region":
+		Init()
+		err := cmd.AlibabaRegion(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "alibaba-vpc-id":
+		Init()
+		err := cmd.AlibabaVpcId(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "alibaba-instance-id":
+		Init()
+		err := cmd.AlibabaInstanceId(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "alibaba-access-key-id":
+		Init()
+		err := cmd.AlibabaAccessKeyId(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "alibaba-access-secret":
+		Init()
+		err := cmd.AlibabaAccessKeySecret(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "ibm-api-key":
+		Init()
+		err := cmd.IbmApiKey(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "ibm-region":
+		Init()
+		err := cmd.IbmRegion(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
 	case "unifi-username":
 		Init()
 		err := cmd.UnifiUsername(flag.Arg(1))
@@ -351,6 +892,41 @@ func main() {
 			panic(err)
 		}
 		break
+	case "vyos-hostname":
+		Init()
+		err := cmd.VyosHostname(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "vyos-key":
+		Init()
+		err := cmd.VyosKey(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "vyos-interface":
+		Init()
+		err := cmd.VyosInterface(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "vyos-port-on":
+		Init()
+		err := cmd.VyosPortOn()
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "vyos-port-off":
+		Init()
+		err := cmd.VyosPortOff()
+		if err != nil {
+			panic(err)
+		}
+		break
 	case "pritunl-hostname":
 		Init()
 		err := cmd.PritunlHostname(flag.Arg(1))
@@ -442,6 +1018,174 @@ func main() {
 			panic(err)
 		}
 		break
+	case "bgp-asn":
+		Init()
+		err := cmd.BgpAsn(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "bgp-router-id":
+		Init()
+		err := cmd.BgpRouterId(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "bgp-hold-time":
+		Init()
+		err := cmd.BgpHoldTime(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "bgp-next-hop":
+		Init()
+		err := cmd.BgpNextHop(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "bgp-next-hop6":
+		Init()
+		err := cmd.BgpNextHop6(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "bgp-neighbor-add":
+		Init()
+		err := cmd.BgpNeighborAdd(flag.Arg(1), flag.Arg(2))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "bgp-neighbor-remove":
+		Init()
+		err := cmd.BgpNeighborRemove(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "bgp-neighbor-port":
+		Init()
+		err := cmd.BgpNeighborPort(flag.Arg(1), flag.Arg(2))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "bgp-neighbor-med":
+		Init()
+		err := cmd.BgpNeighborMed(flag.Arg(1), flag.Arg(2))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "bgp-neighbor-local-pref":
+		Init()
+		err := cmd.BgpNeighborLocalPref(flag.Arg(1), flag.Arg(2))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "bgp-community-add":
+		Init()
+		err := cmd.BgpCommunityAdd(flag.Arg(1), flag.Arg(2))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "bgp-community-clear":
+		Init()
+		err := cmd.BgpCommunityClear(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "frr-next-hop":
+		Init()
+		err := cmd.FrrNextHop(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "frr-next-hop6":
+		Init()
+		err := cmd.FrrNextHop6(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "frr-vrf":
+		Init()
+		err := cmd.FrrVrf(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "frr-tag":
+		Init()
+		err := cmd.FrrTag(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "frr-distance":
+		Init()
+		err := cmd.FrrDistance(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "ssh-host-add":
+		Init()
+		err := cmd.SshAddHost(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "ssh-host-remove":
+		Init()
+		err := cmd.SshRemoveHost(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "ssh-username":
+		Init()
+		err := cmd.SshUsername(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "ssh-private-key":
+		Init()
+		err := cmd.SshPrivateKey(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "ssh-known-hosts":
+		Init()
+		err := cmd.SshKnownHosts(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "ssh-add-command":
+		Init()
+		err := cmd.SshAddCommand(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
+	case "ssh-delete-command":
+		Init()
+		err := cmd.SshDeleteCommand(flag.Arg(1))
+		if err != nil {
+			panic(err)
+		}
+		break
 	default:
 		fmt.Println(help)
 	}
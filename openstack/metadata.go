@@ -0,0 +1,59 @@
+package openstack
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/errortypes"
+)
+
+const metadataUrl = "http://169.254.169.254/openstack/latest/meta_data.json"
+
+var metadataClient = &http.Client{
+	Timeout: 2 * time.Second,
+}
+
+type Metadata struct {
+	InstanceId string `json:"uuid"`
+	Name       string `json:"name"`
+	ProjectId  string `json:"project_id"`
+}
+
+func GetMetadata() (mdata *Metadata, err error) {
+	resp, err := metadataClient.Get(metadataUrl)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "openstack: Failed to get metadata"),
+		}
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		err = &errortypes.RequestError{
+			errors.Newf("openstack: Metadata bad status %d",
+				resp.StatusCode),
+		}
+		return
+	}
+
+	mdata = &Metadata{}
+	err = json.NewDecoder(resp.Body).Decode(mdata)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "openstack: Failed to parse metadata"),
+		}
+		return
+	}
+
+	if mdata.InstanceId == "" {
+		err = &errortypes.ParseError{
+			errors.New("openstack: Missing instance id in metadata"),
+		}
+		return
+	}
+
+	return
+}
@@ -0,0 +1,194 @@
+package openstack
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/errortypes"
+)
+
+type FixedIp struct {
+	SubnetId  string `json:"subnet_id"`
+	IpAddress string `json:"ip_address"`
+}
+
+type AddressPair struct {
+	IpAddress  string `json:"ip_address"`
+	MacAddress string `json:"mac_address,omitempty"`
+}
+
+type Port struct {
+	Id                  string        `json:"id"`
+	NetworkId           string        `json:"network_id"`
+	DeviceId            string        `json:"device_id"`
+	DeviceOwner         string        `json:"device_owner"`
+	FixedIps            []FixedIp     `json:"fixed_ips"`
+	AllowedAddressPairs []AddressPair `json:"allowed_address_pairs"`
+	PortSecurityEnabled *bool         `json:"port_security_enabled"`
+}
+
+type portsResp struct {
+	Ports []*Port `json:"ports"`
+}
+
+type portUpdate struct {
+	AllowedAddressPairs []AddressPair `json:"allowed_address_pairs"`
+}
+
+type portUpdateReq struct {
+	Port portUpdate `json:"port"`
+}
+
+func (p *Port) GetFixedIp(addr string) (fixedIp *FixedIp) {
+	for _, fxdIp := range p.FixedIps {
+		if addr == "" && !strings.Contains(fxdIp.IpAddress, ":") {
+			fixedIp = &fxdIp
+			return
+		}
+
+		if fxdIp.IpAddress == addr {
+			fixedIp = &fxdIp
+			return
+		}
+	}
+
+	return
+}
+
+func (p *Port) HasPortSecurity() bool {
+	return p.PortSecurityEnabled == nil || *p.PortSecurityEnabled
+}
+
+func (p *Port) HasAddressPair(network string) bool {
+	for _, pair := range p.AllowedAddressPairs {
+		if pair.IpAddress == network {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Port) AddAddressPair(pv *Provider, network string) (err error) {
+	if !p.HasPortSecurity() || p.HasAddressPair(network) {
+		return
+	}
+
+	pairs := append([]AddressPair{}, p.AllowedAddressPairs...)
+	pairs = append(pairs, AddressPair{
+		IpAddress: network,
+	})
+
+	err = p.setAddressPairs(pv, pairs)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (p *Port) RemoveAddressPair(pv *Provider, network string) (err error) {
+	if !p.HasPortSecurity() || !p.HasAddressPair(network) {
+		return
+	}
+
+	pairs := []AddressPair{}
+	for _, pair := range p.AllowedAddressPairs {
+		if pair.IpAddress != network {
+			pairs = append(pairs, pair)
+		}
+	}
+
+	err = p.setAddressPairs(pv, pairs)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (p *Port) setAddressPairs(pv *Provider, pairs []AddressPair) (
+	err error) {
+
+	data := &portUpdateReq{
+		Port: portUpdate{
+			AllowedAddressPairs: pairs,
+		},
+	}
+
+	err = pv.Request("PUT", fmt.Sprintf("/v2.0/ports/%s", p.Id), data, nil)
+	if err != nil {
+		return
+	}
+
+	p.AllowedAddressPairs = pairs
+
+	return
+}
+
+func getPorts(pv *Provider, query url.Values) (ports []*Port, err error) {
+	data := &portsResp{}
+
+	err = pv.Request("GET", "/v2.0/ports?"+query.Encode(), nil, data)
+	if err != nil {
+		return
+	}
+
+	ports = data.Ports
+	if ports == nil {
+		ports = []*Port{}
+	}
+
+	return
+}
+
+func GetPort(pv *Provider, portId string) (port *Port, err error) {
+	data := &struct {
+		Port *Port `json:"port"`
+	}{}
+
+	err = pv.Request("GET", fmt.Sprintf("/v2.0/ports/%s", portId),
+		nil, data)
+	if err != nil {
+		return
+	}
+
+	port = data.Port
+	if port == nil {
+		err = &errortypes.RequestError{
+			errors.New("openstack: Port not found"),
+		}
+		return
+	}
+
+	return
+}
+
+func GetInstancePort(pv *Provider, instanceId, localAddr string) (
+	port *Port, err error) {
+
+	ports, err := getPorts(pv, url.Values{
+		"device_id": []string{instanceId},
+	})
+	if err != nil {
+		return
+	}
+
+	for _, prt := range ports {
+		if prt.GetFixedIp(localAddr) != nil {
+			port = prt
+			return
+		}
+	}
+
+	if len(ports) > 0 {
+		port = ports[0]
+		return
+	}
+
+	err = &errortypes.RequestError{
+		errors.New("openstack: Failed to find instance port"),
+	}
+	return
+}
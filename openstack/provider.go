@@ -0,0 +1,285 @@
+package openstack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/config"
+	"github.com/pritunl/pritunl-link/errortypes"
+)
+
+const defaultDomain = "Default"
+
+var client = &http.Client{
+	Timeout: 20 * time.Second,
+}
+
+type Provider struct {
+	token      string
+	networkUrl string
+}
+
+type authDomain struct {
+	Name string `json:"name"`
+}
+
+type authUser struct {
+	Name     string     `json:"name"`
+	Domain   authDomain `json:"domain"`
+	Password string     `json:"password"`
+}
+
+type authPassword struct {
+	User authUser `json:"user"`
+}
+
+type authAppCredential struct {
+	Id     string `json:"id"`
+	Secret string `json:"secret"`
+}
+
+type authIdentity struct {
+	Methods       []string           `json:"methods"`
+	Password      *authPassword      `json:"password,omitempty"`
+	AppCredential *authAppCredential `json:"application_credential,omitempty"`
+}
+
+type authProject struct {
+	Id string `json:"id"`
+}
+
+type authScope struct {
+	Project authProject `json:"project"`
+}
+
+type authData struct {
+	Identity authIdentity `json:"identity"`
+	Scope    *authScope   `json:"scope,omitempty"`
+}
+
+type authReq struct {
+	Auth authData `json:"auth"`
+}
+
+type catalogEndpoint struct {
+	Interface string `json:"interface"`
+	Region    string `json:"region"`
+	RegionId  string `json:"region_id"`
+	Url       string `json:"url"`
+}
+
+type catalogService struct {
+	Type      string            `json:"type"`
+	Endpoints []catalogEndpoint `json:"endpoints"`
+}
+
+type authToken struct {
+	Catalog []catalogService `json:"catalog"`
+}
+
+type authResp struct {
+	Token authToken `json:"token"`
+}
+
+func (p *Provider) Request(method, path string, input, output interface{}) (
+	err error) {
+
+	var body io.Reader
+	if input != nil {
+		data, e := json.Marshal(input)
+		if e != nil {
+			err = &errortypes.ParseError{
+				errors.Wrap(e, "openstack: Failed to marshal request"),
+			}
+			return
+		}
+		body = bytes.NewBuffer(data)
+	}
+
+	req, err := http.NewRequest(method, p.networkUrl+path, body)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "openstack: Request init error"),
+		}
+		return
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Auth-Token", p.token)
+	if input != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrapf(err, "openstack: Request %s %s failed",
+				method, path),
+		}
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		err = &errortypes.RequestError{
+			errors.Newf("openstack: Request %s %s bad status %d '%s'",
+				method, path, resp.StatusCode,
+				strings.TrimSpace(string(respBody))),
+		}
+		return
+	}
+
+	if output != nil {
+		err = json.NewDecoder(resp.Body).Decode(output)
+		if err != nil {
+			err = &errortypes.ParseError{
+				errors.Wrap(err, "openstack: Failed to parse response"),
+			}
+			return
+		}
+	}
+
+	return
+}
+
+func NewProvider() (prov *Provider, err error) {
+	conf := config.Config.Openstack
+
+	if conf.AuthUrl == "" {
+		err = &errortypes.ParseError{
+			errors.New("openstack: Missing auth url"),
+		}
+		return
+	}
+
+	data := &authReq{}
+	if conf.CredentialId != "" {
+		data.Auth.Identity = authIdentity{
+			Methods: []string{"application_credential"},
+			AppCredential: &authAppCredential{
+				Id:     conf.CredentialId,
+				Secret: conf.CredentialSecret,
+			},
+		}
+	} else {
+		domain := conf.Domain
+		if domain == "" {
+			domain = defaultDomain
+		}
+
+		data.Auth.Identity = authIdentity{
+			Methods: []string{"password"},
+			Password: &authPassword{
+				User: authUser{
+					Name: conf.Username,
+					Domain: authDomain{
+						Name: domain,
+					},
+					Password: conf.Password,
+				},
+			},
+		}
+		data.Auth.Scope = &authScope{
+			Project: authProject{
+				Id: conf.ProjectId,
+			},
+		}
+	}
+
+	dataByt, err := json.Marshal(data)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "openstack: Failed to marshal auth request"),
+		}
+		return
+	}
+
+	req, err := http.NewRequest(
+		"POST",
+		fmt.Sprintf("%s/auth/tokens", strings.TrimRight(conf.AuthUrl, "/")),
+		bytes.NewBuffer(dataByt),
+	)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "openstack: Auth request init error"),
+		}
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "openstack: Auth request failed"),
+		}
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 201 {
+		err = &errortypes.RequestError{
+			errors.Newf("openstack: Auth bad status %d", resp.StatusCode),
+		}
+		return
+	}
+
+	token := resp.Header.Get("X-Subject-Token")
+	if token == "" {
+		err = &errortypes.ParseError{
+			errors.New("openstack: Auth missing token"),
+		}
+		return
+	}
+
+	authData := &authResp{}
+	err = json.NewDecoder(resp.Body).Decode(authData)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "openstack: Failed to parse auth response"),
+		}
+		return
+	}
+
+	networkUrl := ""
+	for _, service := range authData.Token.Catalog {
+		if service.Type != "network" {
+			continue
+		}
+
+		for _, endpoint := range service.Endpoints {
+			if endpoint.Interface != "public" {
+				continue
+			}
+
+			if conf.Region != "" && endpoint.RegionId != conf.Region &&
+				endpoint.Region != conf.Region {
+
+				continue
+			}
+
+			networkUrl = strings.TrimRight(endpoint.Url, "/")
+			break
+		}
+	}
+
+	if networkUrl == "" {
+		err = &errortypes.ParseError{
+			errors.New("openstack: Failed to find network endpoint"),
+		}
+		return
+	}
+
+	prov = &Provider{
+		token:      token,
+		networkUrl: networkUrl,
+	}
+
+	return
+}
@@ -0,0 +1,181 @@
+package openstack
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/dropbox/godropbox/container/set"
+)
+
+type Route struct {
+	Destination string `json:"destination"`
+	Nexthop     string `json:"nexthop"`
+}
+
+type Router struct {
+	Id     string   `json:"id"`
+	Name   string   `json:"name"`
+	Routes []*Route `json:"routes"`
+}
+
+type routerResp struct {
+	Router *Router `json:"router"`
+}
+
+type routerRoutes struct {
+	Routes []*Route `json:"routes"`
+}
+
+type routerRoutesReq struct {
+	Router routerRoutes `json:"router"`
+}
+
+func (r *Router) RouteExists(dest, nexthop string) bool {
+	for _, route := range r.Routes {
+		if route.Destination == dest && route.Nexthop == nexthop {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Router) RouteUpsert(pv *Provider, dest, nexthop string) (
+	err error) {
+
+	if r.RouteExists(dest, nexthop) {
+		return
+	}
+
+	remove := []*Route{}
+	for _, route := range r.Routes {
+		if route.Destination == dest && route.Nexthop != nexthop {
+			remove = append(remove, route)
+		}
+	}
+
+	if len(remove) > 0 {
+		err = r.updateRoutes(pv, "remove_extraroutes", remove)
+		if err != nil {
+			return
+		}
+	}
+
+	err = r.updateRoutes(pv, "add_extraroutes", []*Route{
+		&Route{
+			Destination: dest,
+			Nexthop:     nexthop,
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (r *Router) RouteRemove(pv *Provider, dest, nexthop string) (
+	err error) {
+
+	if !r.RouteExists(dest, nexthop) {
+		return
+	}
+
+	err = r.updateRoutes(pv, "remove_extraroutes", []*Route{
+		&Route{
+			Destination: dest,
+			Nexthop:     nexthop,
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (r *Router) updateRoutes(pv *Provider, action string,
+	routes []*Route) (err error) {
+
+	data := &routerRoutesReq{
+		Router: routerRoutes{
+			Routes: routes,
+		},
+	}
+	resp := &routerResp{}
+
+	err = pv.Request("PUT", fmt.Sprintf("/v2.0/routers/%s/%s",
+		r.Id, action), data, resp)
+	if err != nil {
+		return
+	}
+
+	if resp.Router != nil {
+		r.Routes = resp.Router.Routes
+	}
+
+	return
+}
+
+func GetRouter(pv *Provider, routerId string) (router *Router, err error) {
+	data := &routerResp{}
+
+	err = pv.Request("GET", fmt.Sprintf("/v2.0/routers/%s", routerId),
+		nil, data)
+	if err != nil {
+		return
+	}
+
+	router = data.Router
+	if router == nil {
+		router = &Router{
+			Id: routerId,
+		}
+	}
+
+	return
+}
+
+func GetPortRouterIds(pv *Provider, port *Port) (
+	routerIds []string, err error) {
+
+	subnetIds := set.NewSet()
+	for _, fixedIp := range port.FixedIps {
+		subnetIds.Add(fixedIp.SubnetId)
+	}
+
+	ports, err := getPorts(pv, url.Values{
+		"network_id": []string{port.NetworkId},
+	})
+	if err != nil {
+		return
+	}
+
+	routerIds = []string{}
+	routerIdsSet := set.NewSet()
+
+	for _, prt := range ports {
+		if !strings.HasPrefix(prt.DeviceOwner, "network:router_interface") &&
+			prt.DeviceOwner != "network:ha_router_replicated_interface" {
+
+			continue
+		}
+
+		attached := false
+		for _, fixedIp := range prt.FixedIps {
+			if subnetIds.Contains(fixedIp.SubnetId) {
+				attached = true
+				break
+			}
+		}
+
+		if !attached || routerIdsSet.Contains(prt.DeviceId) {
+			continue
+		}
+
+		routerIdsSet.Add(prt.DeviceId)
+		routerIds = append(routerIds, prt.DeviceId)
+	}
+
+	return
+}
@@ -0,0 +1,52 @@
+package routes
+
+type AlibabaRoute struct {
+	DestNetwork string `json:"dest_network"`
+	Region      string `json:"region"`
+	VpcId       string `json:"vpc_id"`
+	InstanceId  string `json:"instance_id"`
+}
+
+func (r *AlibabaRoute) Add() (err error) {
+	routes, err := GetCurrent()
+	if err != nil {
+		return
+	}
+
+	if routes.Alibaba == nil {
+		routes.Alibaba = map[string]*AlibabaRoute{}
+	}
+
+	routes.Alibaba[r.DestNetwork] = r
+
+	err = routes.Commit()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (r *AlibabaRoute) Remove() (err error) {
+	routes, err := GetCurrent()
+	if err != nil {
+		return
+	}
+
+	if routes.Alibaba != nil {
+		if _, ok := routes.Alibaba[r.DestNetwork]; ok {
+			delete(routes.Alibaba, r.DestNetwork)
+		}
+
+		if len(routes.Alibaba) == 0 {
+			routes.Alibaba = nil
+		}
+	}
+
+	err = routes.Commit()
+	if err != nil {
+		return
+	}
+
+	return
+}
@@ -0,0 +1,53 @@
+package routes
+
+type FrrRoute struct {
+	Network  string `json:"network"`
+	Nexthop  string `json:"nexthop"`
+	Vrf      string `json:"vrf"`
+	Tag      int    `json:"tag"`
+	Distance int    `json:"distance"`
+}
+
+func (r *FrrRoute) Add() (err error) {
+	routes, err := GetCurrent()
+	if err != nil {
+		return
+	}
+
+	if routes.Frr == nil {
+		routes.Frr = map[string]*FrrRoute{}
+	}
+
+	routes.Frr[r.Network] = r
+
+	err = routes.Commit()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (r *FrrRoute) Remove() (err error) {
+	routes, err := GetCurrent()
+	if err != nil {
+		return
+	}
+
+	if routes.Frr != nil {
+		if _, ok := routes.Frr[r.Network]; ok {
+			delete(routes.Frr, r.Network)
+		}
+
+		if len(routes.Frr) == 0 {
+			routes.Frr = nil
+		}
+	}
+
+	err = routes.Commit()
+	if err != nil {
+		return
+	}
+
+	return
+}
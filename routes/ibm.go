@@ -0,0 +1,53 @@
+package routes
+
+type IbmRoute struct {
+	DestNetwork string `json:"dest_network"`
+	Region      string `json:"region"`
+	Zone        string `json:"zone"`
+	VpcId       string `json:"vpc_id"`
+	NextHop     string `json:"next_hop"`
+}
+
+func (r *IbmRoute) Add() (err error) {
+	routes, err := GetCurrent()
+	if err != nil {
+		return
+	}
+
+	if routes.Ibm == nil {
+		routes.Ibm = map[string]*IbmRoute{}
+	}
+
+	routes.Ibm[r.DestNetwork] = r
+
+	err = routes.Commit()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (r *IbmRoute) Remove() (err error) {
+	routes, err := GetCurrent()
+	if err != nil {
+		return
+	}
+
+	if routes.Ibm != nil {
+		if _, ok := routes.Ibm[r.DestNetwork]; ok {
+			delete(routes.Ibm, r.DestNetwork)
+		}
+
+		if len(routes.Ibm) == 0 {
+			routes.Ibm = nil
+		}
+	}
+
+	err = routes.Commit()
+	if err != nil {
+		return
+	}
+
+	return
+}
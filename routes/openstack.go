@@ -0,0 +1,52 @@
+package routes
+
+type OpenstackRoute struct {
+	DestNetwork string   `json:"dest_network"`
+	PortId      string   `json:"port_id"`
+	RouterIds   []string `json:"router_ids"`
+	Nexthop     string   `json:"nexthop"`
+}
+
+func (r *OpenstackRoute) Add() (err error) {
+	routes, err := GetCurrent()
+	if err != nil {
+		return
+	}
+
+	if routes.Openstack == nil {
+		routes.Openstack = map[string]*OpenstackRoute{}
+	}
+
+	routes.Openstack[r.DestNetwork] = r
+
+	err = routes.Commit()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (r *OpenstackRoute) Remove() (err error) {
+	routes, err := GetCurrent()
+	if err != nil {
+		return
+	}
+
+	if routes.Openstack != nil {
+		if _, ok := routes.Openstack[r.DestNetwork]; ok {
+			delete(routes.Openstack, r.DestNetwork)
+		}
+
+		if len(routes.Openstack) == 0 {
+			routes.Openstack = nil
+		}
+	}
+
+	err = routes.Commit()
+	if err != nil {
+		return
+	}
+
+	return
+}
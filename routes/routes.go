@@ -14,14 +14,20 @@ import (
 )
 
 type CurrentRoutes struct {
-	Aws     map[string]*AwsRoute     `json:"aws"`
-	Azure   map[string]*AzureRoute   `json:"azure"`
-	Google  map[string]*GoogleRoute  `json:"google"`
-	Oracle  map[string]*OracleRoute  `json:"oracle"`
-	Unifi   map[string]*UnifiRoute   `json:"unifi"`
-	Edge    map[string]*EdgeRoute    `json:"edge"`
-	Pritunl map[string]*PritunlRoute `json:"pritunl"`
-	Hetzner map[string]*HetznerRoute `json:"hetzner"`
+	Aws       map[string]*AwsRoute       `json:"aws"`
+	Azure     map[string]*AzureRoute     `json:"azure"`
+	Alibaba   map[string]*AlibabaRoute   `json:"alibaba"`
+	Ibm       map[string]*IbmRoute       `json:"ibm"`
+	Google    map[string]*GoogleRoute    `json:"google"`
+	Oracle    map[string]*OracleRoute    `json:"oracle"`
+	Unifi     map[string]*UnifiRoute     `json:"unifi"`
+	Edge      map[string]*EdgeRoute      `json:"edge"`
+	Pritunl   map[string]*PritunlRoute   `json:"pritunl"`
+	Hetzner   map[string]*HetznerRoute   `json:"hetzner"`
+	Vyos      map[string]*VyosRoute      `json:"vyos"`
+	Openstack map[string]*OpenstackRoute `json:"openstack"`
+	Frr       map[string]*FrrRoute       `json:"frr"`
+	Ssh       map[string]*SshRoute       `json:"ssh"`
 }
 
 func (c *CurrentRoutes) Commit() (err error) {
@@ -105,6 +111,22 @@ func GetDiff(destNetworks []string) (routes *CurrentRoutes, err error) {
 		}
 	}
 
+	if config.Config.Provider == "alibaba" {
+		for destNetwork := range routes.Alibaba {
+			if destNetworksSet.Contains(destNetwork) {
+				delete(routes.Alibaba, destNetwork)
+			}
+		}
+	}
+
+	if config.Config.Provider == "ibm" {
+		for destNetwork := range routes.Ibm {
+			if destNetworksSet.Contains(destNetwork) {
+				delete(routes.Ibm, destNetwork)
+			}
+		}
+	}
+
 	if config.Config.Provider == "google" {
 		for destNetwork := range routes.Google {
 			if destNetworksSet.Contains(destNetwork) {
@@ -121,6 +143,14 @@ func GetDiff(destNetworks []string) (routes *CurrentRoutes, err error) {
 		}
 	}
 
+	if config.Config.Provider == "openstack" {
+		for destNetwork := range routes.Openstack {
+			if destNetworksSet.Contains(destNetwork) {
+				delete(routes.Openstack, destNetwork)
+			}
+		}
+	}
+
 	if config.Config.Provider == "hetzner" {
 		for destNetwork := range routes.Hetzner {
 			if destNetworksSet.Contains(destNetwork) {
@@ -145,6 +175,30 @@ func GetDiff(destNetworks []string) (routes *CurrentRoutes, err error) {
 		}
 	}
 
+	if config.Config.Provider == "vyos" {
+		for destNetwork := range routes.Vyos {
+			if destNetworksSet.Contains(destNetwork) {
+				delete(routes.Vyos, destNetwork)
+			}
+		}
+	}
+
+	if config.Config.Provider == "frr" {
+		for destNetwork := range routes.Frr {
+			if destNetworksSet.Contains(destNetwork) {
+				delete(routes.Frr, destNetwork)
+			}
+		}
+	}
+
+	if config.Config.Provider == "ssh" {
+		for destNetwork := range routes.Ssh {
+			if destNetworksSet.Contains(destNetwork) {
+				delete(routes.Ssh, destNetwork)
+			}
+		}
+	}
+
 	if config.Config.Provider == "pritunl" {
 		for destNetwork := range routes.Pritunl {
 			if destNetworksSet.Contains(destNetwork) {
@@ -0,0 +1,51 @@
+package routes
+
+type SshRoute struct {
+	Network string   `json:"network"`
+	Nexthop string   `json:"nexthop"`
+	Hosts   []string `json:"hosts"`
+}
+
+func (r *SshRoute) Add() (err error) {
+	routes, err := GetCurrent()
+	if err != nil {
+		return
+	}
+
+	if routes.Ssh == nil {
+		routes.Ssh = map[string]*SshRoute{}
+	}
+
+	routes.Ssh[r.Network] = r
+
+	err = routes.Commit()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (r *SshRoute) Remove() (err error) {
+	routes, err := GetCurrent()
+	if err != nil {
+		return
+	}
+
+	if routes.Ssh != nil {
+		if _, ok := routes.Ssh[r.Network]; ok {
+			delete(routes.Ssh, r.Network)
+		}
+
+		if len(routes.Ssh) == 0 {
+			routes.Ssh = nil
+		}
+	}
+
+	err = routes.Commit()
+	if err != nil {
+		return
+	}
+
+	return
+}
@@ -0,0 +1,50 @@
+package routes
+
+type VyosRoute struct {
+	Network string `json:"network"`
+	Nexthop string `json:"nexthop"`
+}
+
+func (r *VyosRoute) Add() (err error) {
+	routes, err := GetCurrent()
+	if err != nil {
+		return
+	}
+
+	if routes.Vyos == nil {
+		routes.Vyos = map[string]*VyosRoute{}
+	}
+
+	routes.Vyos[r.Network] = r
+
+	err = routes.Commit()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (r *VyosRoute) Remove() (err error) {
+	routes, err := GetCurrent()
+	if err != nil {
+		return
+	}
+
+	if routes.Vyos != nil {
+		if _, ok := routes.Vyos[r.Network]; ok {
+			delete(routes.Vyos, r.Network)
+		}
+
+		if len(routes.Vyos) == 0 {
+			routes.Vyos = nil
+		}
+	}
+
+	err = routes.Commit()
+	if err != nil {
+		return
+	}
+
+	return
+}
@@ -13,6 +13,7 @@ var (
 	Status           = map[string]string{}
 	IsDirectClient   = false
 	DirectIpsecState *State
+	WgModes          = map[string]string{}
 )
 
 type State struct {
@@ -31,6 +32,10 @@ type State struct {
 	PreferredIke   string            `json:"preferred_ike"`
 	PreferredEsp   string            `json:"preferred_esp"`
 	ForcePreferred bool              `json:"force_preferred"`
+	IpsecAuth      string            `json:"ipsec_auth"`
+	IpsecCert      string            `json:"ipsec_cert"`
+	IpsecCaCerts   []string          `json:"ipsec_ca_certs"`
+	WgNextKey      string            `json:"wg_next_public_key"`
 }
 
 func (s *State) Copy() *State {
@@ -50,6 +55,10 @@ func (s *State) Copy() *State {
 		PreferredIke:   s.PreferredIke,
 		PreferredEsp:   s.PreferredEsp,
 		ForcePreferred: s.ForcePreferred,
+		IpsecAuth:      s.IpsecAuth,
+		IpsecCert:      s.IpsecCert,
+		IpsecCaCerts:   s.IpsecCaCerts,
+		WgNextKey:      s.WgNextKey,
 	}
 }
 
@@ -59,6 +68,7 @@ type Link struct {
 	Hash         string   `json:"hash"`
 	PreSharedKey string   `json:"pre_shared_key"`
 	Right        string   `json:"right"`
+	RightId      string   `json:"right_id"`
 	WgPublicKey  string   `json:"wg_public_key"`
 	LeftSubnets  []string `json:"left_subnets"`
 	RightSubnets []string `json:"right_subnets"`
@@ -13,6 +13,7 @@ import (
 var (
 	offlineTime   time.Time
 	lastReconnect = time.Now()
+	SaStatus      = status.SaInfos{}
 )
 
 func Unknown(states []*State) (unknownIds []string, err error) {
@@ -65,6 +66,7 @@ func Update(states []*State) (hasConnected bool,
 	hasIpsec := false
 	hasWg := false
 	wgKeyMap := map[string]string{}
+	wgTimeouts := map[string]time.Duration{}
 	for _, stat := range states {
 		if stat.Protocol == "wg" {
 			hasWg = true
@@ -75,6 +77,8 @@ func Update(states []*State) (hasConnected bool,
 			if stat.Protocol == "wg" {
 				wgKeyMap[lnk.WgPublicKey] = fmt.Sprintf(
 					"%s-%s-%s", stat.Id, lnk.Id, lnk.Hash)
+				wgTimeouts[lnk.WgPublicKey] = time.Duration(
+					config.GetWgData(stat.Id).HandshakeTimeout) * time.Second
 			} else if stat.Protocol == "" || stat.Protocol == "ipsec" {
 				names.Add(GetLinkId(stat.Id, lnk.Id, lnk.Hash))
 			}
@@ -83,20 +87,27 @@ func Update(states []*State) (hasConnected bool,
 
 	ipsecStats := status.Status{}
 	wgStats := status.Status{}
+	saStats := status.SaInfos{}
 	if hasIpsec {
 		ipsecStats, err = status.Get()
 		if err != nil {
 			return
 		}
+
+		saStats, err = status.GetSaInfo()
+		if err != nil {
+			return
+		}
 	}
 	if hasWg {
-		wgStats, err = status.GetWg(wgKeyMap)
+		wgStats, err = status.GetWg(wgKeyMap, wgTimeouts)
 		if err != nil {
 			return
 		}
 	}
 
 	Status = ipsecStats.Merge(wgStats)
+	SaStatus = saStats
 
 	for connId, connStatus := range ipsecStats {
 		if connStatus == "connected" {
@@ -141,3 +152,59 @@ func Update(states []*State) (hasConnected bool,
 
 	return
 }
+
+func GetConnectedNetworks(states []*State) (networks []string) {
+	networks = []string{}
+	added := set.NewSet()
+
+	for _, stat := range states {
+		if stat.Type == DirectClient || stat.Type == DirectServer {
+			continue
+		}
+
+		for _, lnk := range stat.Links {
+			connected := map[int]bool{}
+
+			if stat.Protocol == "wg" {
+				if GetStatus(fmt.Sprintf("%s-%s-%s",
+					stat.Id, lnk.Id, lnk.Hash)) == "connected" {
+
+					for y := range lnk.RightSubnets {
+						connected[y] = true
+					}
+				}
+			} else if stat.Protocol == "" || stat.Protocol == "ipsec" {
+				if GetStatus(GetLinkId(
+					stat.Id, lnk.Id, lnk.Hash)) == "connected" {
+
+					for y := range lnk.RightSubnets {
+						connected[y] = true
+					}
+				}
+
+				if lnk.Static && (len(lnk.LeftSubnets) > 1 ||
+					len(lnk.RightSubnets) > 1) {
+
+					for x := range lnk.LeftSubnets {
+						for y := range lnk.RightSubnets {
+							if GetStatus(GetLinkIds(stat.Id, lnk.Id,
+								x, y, lnk.Hash)) == "connected" {
+
+								connected[y] = true
+							}
+						}
+					}
+				}
+			}
+
+			for y, network := range lnk.RightSubnets {
+				if connected[y] && !added.Contains(network) {
+					added.Add(network)
+					networks = append(networks, network)
+				}
+			}
+		}
+	}
+
+	return
+}
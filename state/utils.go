@@ -27,6 +27,7 @@ import (
 	"github.com/pritunl/pritunl-link/errortypes"
 	"github.com/pritunl/pritunl-link/interlink"
 	"github.com/pritunl/pritunl-link/iptables"
+	"github.com/pritunl/pritunl-link/status"
 	"github.com/pritunl/pritunl-link/utils"
 	"github.com/sirupsen/logrus"
 )
@@ -61,6 +62,12 @@ type hostState struct {
 	Latency int  `json:"latency"`
 }
 
+type aclState struct {
+	Action  string `json:"action"`
+	Packets uint64 `json:"packets"`
+	Bytes   uint64 `json:"bytes"`
+}
+
 type stateData struct {
 	Timestamp     int64                 `json:"timestamp"`
 	Version       string                `json:"version"`
@@ -68,8 +75,13 @@ type stateData struct {
 	LocalAddress  string                `json:"local_address"`
 	Address6      string                `json:"address6"`
 	WgPublicKey   string                `json:"wg_public_key"`
+	WgNextKey     string                `json:"wg_next_public_key,omitempty"`
+	WgMode        string                `json:"wg_mode,omitempty"`
+	IpsecCsr      string                `json:"ipsec_csr,omitempty"`
 	Status        map[string]string     `json:"status"`
 	Hosts         map[string]*hostState `json:"hosts"`
+	Acls          map[string]*aclState  `json:"acls,omitempty"`
+	Sas           status.SaInfos        `json:"sas,omitempty"`
 	Errors        []string              `json:"errors"`
 }
 
@@ -255,6 +267,38 @@ func getState(stateId, stateSecret, host, cacheKey string, timestamp int64,
 	return
 }
 
+func getAclsStatus(stateId string) (aclsStatus map[string]*aclState) {
+	if len(config.Config.Acls) == 0 {
+		return
+	}
+
+	counters, err := iptables.GetAclCounters()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("state: Failed to get acl counters")
+		return
+	}
+
+	aclsStatus = map[string]*aclState{}
+	for _, acl := range config.Config.Acls {
+		if acl.State != "" && acl.State != stateId {
+			continue
+		}
+
+		aclStatus := &aclState{
+			Action: acl.Action,
+		}
+		if counter := counters[acl.Id]; counter != nil {
+			aclStatus.Packets = counter.Packets
+			aclStatus.Bytes = counter.Bytes
+		}
+		aclsStatus[acl.Id] = aclStatus
+	}
+
+	return
+}
+
 func GetState(uri string) (state *State, hosts []string, err error) {
 	if constants.Interrupt {
 		err = &errortypes.UnknownError{
@@ -271,6 +315,7 @@ func GetState(uri string) (state *State, hosts []string, err error) {
 		return
 	}
 
+	stateSas := status.SaInfos{}
 	status := Status
 	stateId := uriData.User.Username()
 	stateSecret, _ := uriData.User.Password()
@@ -294,10 +339,52 @@ func GetState(uri string) (state *State, hosts []string, err error) {
 		}
 	}
 
+	saStatus := SaStatus
+
+	for connId, saInfo := range saStatus {
+		connIds := strings.Split(connId, "-")
+		if len(connIds) != 3 {
+			continue
+		}
+
+		if connIds[0] != stateId {
+			continue
+		}
+
+		if stateSas[connIds[1]] == nil || status[connId] == "connected" {
+			stateSas[connIds[1]] = saInfo
+		}
+	}
+
 	pubKey, err := config.State.GetPublicKey(stateId)
 	if err != nil {
 		return
 	}
+	nextPubKey := config.State.GetNextPublicKey(stateId)
+
+	csr := ""
+	prevState := getStateCache(uri)
+	if prevState != nil &&
+		(prevState.Protocol == "" || prevState.Protocol == "ipsec") &&
+		(prevState.IpsecAuth == "cert" || prevState.IpsecCert != "") {
+
+		csrAddrs := []string{}
+		if addr := GetPublicAddress(); addr != "" {
+			csrAddrs = append(csrAddrs, addr)
+		}
+		if addr6 := GetAddress6(); addr6 != "" {
+			csrAddrs = append(csrAddrs, addr6)
+		}
+
+		var e error
+		csr, e = config.State.GetIpsecCsr(stateId, csrAddrs)
+		if e != nil {
+			logrus.WithFields(logrus.Fields{
+				"state_id": stateId,
+				"error":    e,
+			}).Warn("state: Failed to generate ipsec csr")
+		}
+	}
 
 	hosts = []string{}
 	hostsMap := stateHosts[uri]
@@ -338,6 +425,8 @@ func GetState(uri string) (state *State, hosts []string, err error) {
 		waiter.Wait()
 	}
 
+	aclsStatus := getAclsStatus(stateId)
+
 	timestamp := time.Now().Unix()
 
 	data := &stateData{
@@ -347,8 +436,13 @@ func GetState(uri string) (state *State, hosts []string, err error) {
 		LocalAddress:  GetLocalAddress(),
 		Address6:      GetAddress6(),
 		WgPublicKey:   pubKey,
+		WgNextKey:     nextPubKey,
+		WgMode:        WgModes[stateId],
+		IpsecCsr:      csr,
 		Status:        stateStatus,
 		Hosts:         hostsStatus,
+		Acls:          aclsStatus,
+		Sas:           stateSas,
 	}
 
 	dataByt, err := json.Marshal(data)
@@ -466,6 +560,27 @@ func GetState(uri string) (state *State, hosts []string, err error) {
 		return
 	}
 
+	if nextPubKey != "" && state.WgNextKey == nextPubKey {
+		e := config.State.SetNextReported(stateId, nextPubKey)
+		if e != nil {
+			logrus.WithFields(logrus.Fields{
+				"state_id": stateId,
+				"error":    e,
+			}).Warn("state: Failed to store wg key rotation state")
+		}
+	}
+
+	if state.IpsecCert != "" {
+		e := config.State.SetIpsecCert(
+			stateId, state.IpsecCert, state.IpsecCaCerts)
+		if e != nil {
+			logrus.WithFields(logrus.Fields{
+				"state_id": stateId,
+				"error":    e,
+			}).Warn("state: Failed to store ipsec certificate")
+		}
+	}
+
 	cache := &stateCache{
 		Timestamp: time.Now(),
 		State:     state,
@@ -0,0 +1,132 @@
+package status
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pritunl/pritunl-link/utils"
+)
+
+var (
+	saDurationRe = regexp.MustCompile(
+		`(?:rekeying|reauthentication) in ([0-9]+) (second|minute|hour|day)`)
+	saKeRe = regexp.MustCompile(`^KE[0-9]+_`)
+)
+
+type SaInfo struct {
+	IkeProposal string `json:"ike_proposal"`
+	EspProposal string `json:"esp_proposal"`
+	DhGroup     string `json:"dh_group"`
+	IkeRekey    int    `json:"ike_rekey"`
+	EspRekey    int    `json:"esp_rekey"`
+	NatT        bool   `json:"nat_t"`
+	Mobike      bool   `json:"mobike"`
+}
+
+type SaInfos map[string]*SaInfo
+
+func parseRekey(line string) int {
+	match := saDurationRe.FindStringSubmatch(line)
+	if match == nil {
+		return 0
+	}
+
+	n, _ := strconv.Atoi(match[1])
+	switch match[2] {
+	case "minute":
+		n *= 60
+	case "hour":
+		n *= 3600
+	case "day":
+		n *= 86400
+	}
+
+	return n
+}
+
+func parseDhGroup(proposal string) string {
+	groups := []string{}
+	for _, part := range strings.Split(proposal, "/") {
+		if strings.HasPrefix(part, "MODP_") ||
+			strings.HasPrefix(part, "ECP_") ||
+			strings.HasPrefix(part, "CURVE_") ||
+			strings.HasPrefix(part, "ML_KEM_") ||
+			saKeRe.MatchString(part) {
+
+			groups = append(groups, part)
+		}
+	}
+	return strings.Join(groups, "/")
+}
+
+func GetSaInfo() (infos SaInfos, err error) {
+	infos = SaInfos{}
+
+	output, err := utils.ExecOutput("", "ipsec", "statusall")
+	if err != nil {
+		err = nil
+		return
+	}
+
+	ikeSas := map[string]*SaInfo{}
+
+	for _, line := range strings.Split(output, "\n") {
+		lines := strings.SplitN(line, ":", 2)
+		if len(lines) != 2 {
+			continue
+		}
+
+		name := strings.TrimSpace(lines[0])
+		value := strings.TrimSpace(lines[1])
+
+		if strings.HasSuffix(name, "]") {
+			connId := strings.SplitN(name, "[", 2)[0]
+			ikeSa := ikeSas[connId]
+			if ikeSa == nil {
+				ikeSa = &SaInfo{}
+				ikeSas[connId] = ikeSa
+			}
+
+			if strings.HasPrefix(value, "IKE proposal:") {
+				ikeSa.IkeProposal = strings.TrimSpace(
+					strings.TrimPrefix(value, "IKE proposal:"))
+				ikeSa.DhGroup = parseDhGroup(ikeSa.IkeProposal)
+			} else if strings.HasPrefix(value, "IKEv") {
+				ikeSa.IkeRekey = parseRekey(value)
+			}
+
+			if strings.Contains(value, "MOBIKE") {
+				ikeSa.Mobike = true
+			}
+		} else if strings.HasSuffix(name, "}") {
+			connId := strings.SplitN(name, "{", 2)[0]
+			info := infos[connId]
+			if info == nil {
+				info = &SaInfo{}
+				infos[connId] = info
+			}
+
+			if strings.HasPrefix(value, "INSTALLED") {
+				info.NatT = strings.Contains(value, "ESP in UDP")
+			} else if strings.Contains(value, "bytes_i") {
+				info.EspProposal = strings.SplitN(value, ",", 2)[0]
+				info.EspRekey = parseRekey(value)
+			}
+		}
+	}
+
+	for connId, info := range infos {
+		ikeSa := ikeSas[connId]
+		if ikeSa == nil {
+			continue
+		}
+
+		info.IkeProposal = ikeSa.IkeProposal
+		info.DhGroup = ikeSa.DhGroup
+		info.IkeRekey = ikeSa.IkeRekey
+		info.Mobike = ikeSa.Mobike
+	}
+
+	return
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/dropbox/godropbox/container/set"
 	"github.com/pritunl/pritunl-link/utils"
 	"github.com/sirupsen/logrus"
+	"golang.zx2c4.com/wireguard/wgctrl"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 )
 
 type Status map[string]string
@@ -94,7 +96,9 @@ func Get() (status Status, err error) {
 	return
 }
 
-func GetWg(wgKeyMap map[string]string) (status Status, err error) {
+func GetWg(wgKeyMap map[string]string,
+	wgTimeouts map[string]time.Duration) (status Status, err error) {
+
 	status = Status{}
 
 	output, err := utils.ExecOutput("", "wg", "show", "all", "dump")
@@ -123,8 +127,13 @@ func GetWg(wgKeyMap map[string]string) (status Status, err error) {
 			handshakeTime = time.Unix(handshakeUnix, 0)
 		}
 
+		timeout := wgTimeouts[pubKey]
+		if timeout == 0 {
+			timeout = 3 * time.Minute
+		}
+
 		connState := ""
-		if now.Sub(handshakeTime) < 3*time.Minute {
+		if now.Sub(handshakeTime) < timeout {
 			connState = "connected"
 		} else {
 			connState = "disconnected"
@@ -169,3 +178,34 @@ func GetIds() (connIds []string, err error) {
 
 	return
 }
+
+func GetWgModes() (modes map[string]string, err error) {
+	modes = map[string]string{}
+
+	client, err := wgctrl.New()
+	if err != nil {
+		err = nil
+		return
+	}
+	defer client.Close()
+
+	devices, err := client.Devices()
+	if err != nil {
+		err = nil
+		return
+	}
+
+	for _, device := range devices {
+		if !strings.HasPrefix(device.Name, "wgp") {
+			continue
+		}
+
+		if device.Type == wgtypes.Userspace {
+			modes[device.Name] = "userspace"
+		} else {
+			modes[device.Name] = "kernel"
+		}
+	}
+
+	return
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/bgp"
 	"github.com/pritunl/pritunl-link/config"
 	"github.com/pritunl/pritunl-link/constants"
 	"github.com/pritunl/pritunl-link/errortypes"
@@ -63,6 +64,8 @@ func SyncStatus() {
 		}).Info("sync: Failed to get status")
 	}
 
+	bgp.SetPrefixes(state.GetConnectedNetworks(states))
+
 	if resetLinks != nil && len(resetLinks) != 0 {
 		if hasConnected {
 			logrus.Warn("sync: Disconnected timeout resetting")
@@ -388,6 +391,22 @@ func runSyncPublicAddress6() {
 	}
 }
 
+func runSyncIpTables() {
+	for {
+		time.Sleep(30 * time.Second)
+		if constants.Interrupt {
+			continue
+		}
+
+		err := iptables.CheckIpTables()
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+			}).Info("sync: Failed to check iptables")
+		}
+	}
+}
+
 func SyncConfig() (err error) {
 	if constants.Interrupt {
 		return
@@ -439,12 +458,12 @@ func runSyncConfig() {
 			curFirewall = config.Config.Firewall
 			if config.Config.Firewall {
 				iptables.ClearAcceptIpTables()
-				iptables.ClearWgIpset()
+				iptables.ClearIpsets()
 				iptables.ResetFirewall()
 			} else {
 				iptables.ClearAcceptIpTables()
 				iptables.ClearDropIpTables()
-				iptables.RemoveWgIpset()
+				iptables.RemoveIpsets()
 			}
 		}
 	}
@@ -477,7 +496,7 @@ func Init() {
 	if !config.Config.Firewall {
 		iptables.ClearAcceptIpTables()
 		iptables.ClearDropIpTables()
-		iptables.RemoveWgIpset()
+		iptables.RemoveIpsets()
 	}
 
 	SyncStates()
@@ -488,4 +507,5 @@ func Init() {
 	go runSyncStates()
 	go runSyncStatus()
 	go runSyncConfig()
+	go runSyncIpTables()
 }
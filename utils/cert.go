@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/errortypes"
+)
+
+func GenerateCertKey() (keyPem string, err error) {
+	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "utils: Failed to generate cert key"),
+		}
+		return
+	}
+
+	keyByt, err := x509.MarshalECPrivateKey(privKey)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "utils: Failed to marshal cert key"),
+		}
+		return
+	}
+
+	keyPem = string(pem.EncodeToMemory(&pem.Block{
+		Type:  "EC PRIVATE KEY",
+		Bytes: keyByt,
+	}))
+
+	return
+}
+
+func parseCertKey(keyPem string) (privKey *ecdsa.PrivateKey, err error) {
+	block, _ := pem.Decode([]byte(keyPem))
+	if block == nil {
+		err = &errortypes.ParseError{
+			errors.New("utils: Failed to decode cert key"),
+		}
+		return
+	}
+
+	privKey, err = x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "utils: Failed to parse cert key"),
+		}
+		return
+	}
+
+	return
+}
+
+func GenerateCsr(keyPem, commonName string, addrs []string) (
+	csrPem string, err error) {
+
+	privKey, err := parseCertKey(keyPem)
+	if err != nil {
+		return
+	}
+
+	ips := []net.IP{}
+	for _, addr := range addrs {
+		ip := net.ParseIP(addr)
+		if ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+
+	csrByt, err := x509.CreateCertificateRequest(
+		rand.Reader,
+		&x509.CertificateRequest{
+			Subject: pkix.Name{
+				CommonName: commonName,
+			},
+			IPAddresses: ips,
+		},
+		privKey,
+	)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "utils: Failed to create csr"),
+		}
+		return
+	}
+
+	csrPem = string(pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE REQUEST",
+		Bytes: csrByt,
+	}))
+
+	return
+}
+
+func GetCsrAddresses(csrPem string) (addrs []string) {
+	addrs = []string{}
+
+	block, _ := pem.Decode([]byte(csrPem))
+	if block == nil {
+		return
+	}
+
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return
+	}
+
+	for _, ip := range csr.IPAddresses {
+		addrs = append(addrs, ip.String())
+	}
+	sort.Strings(addrs)
+
+	return
+}
+
+func CheckCert(keyPem, certPem string) bool {
+	privKey, err := parseCertKey(keyPem)
+	if err != nil {
+		return false
+	}
+
+	block, _ := pem.Decode([]byte(certPem))
+	if block == nil {
+		return false
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false
+	}
+
+	now := time.Now()
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return false
+	}
+
+	pubKey, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return false
+	}
+
+	return pubKey.Equal(&privKey.PublicKey)
+}
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/json"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-link/errortypes"
+	"golang.org/x/crypto/scrypt"
+)
+
+type sealedData struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+func getSealCipher(passphrase string, salt []byte) (
+	aead cipher.AEAD, err error) {
+
+	key, err := scrypt.Key([]byte(passphrase), salt, 32768, 8, 1, 32)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "utils: Failed to derive seal key"),
+		}
+		return
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "utils: Failed to load seal cipher"),
+		}
+		return
+	}
+
+	aead, err = cipher.NewGCM(block)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "utils: Failed to load seal cipher"),
+		}
+		return
+	}
+
+	return
+}
+
+func Seal(passphrase string, data []byte) (sealed []byte, err error) {
+	if passphrase == "" {
+		err = &errortypes.ParseError{
+			errors.New("utils: Missing seal passphrase"),
+		}
+		return
+	}
+
+	salt, err := RandBytes(16)
+	if err != nil {
+		return
+	}
+
+	aead, err := getSealCipher(passphrase, salt)
+	if err != nil {
+		return
+	}
+
+	nonce, err := RandBytes(aead.NonceSize())
+	if err != nil {
+		return
+	}
+
+	sealed, err = json.Marshal(&sealedData{
+		Version: 1,
+		Salt:    salt,
+		Nonce:   nonce,
+		Data:    aead.Seal(nil, nonce, data, nil),
+	})
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "utils: Failed to marshal sealed data"),
+		}
+		return
+	}
+
+	return
+}
+
+func Unseal(passphrase string, sealed []byte) (data []byte, err error) {
+	sealedDat := &sealedData{}
+	err = json.Unmarshal(sealed, sealedDat)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "utils: Failed to unmarshal sealed data"),
+		}
+		return
+	}
+
+	if sealedDat.Version != 1 {
+		err = &errortypes.ParseError{
+			errors.Newf("utils: Unknown sealed data version %d",
+				sealedDat.Version),
+		}
+		return
+	}
+
+	aead, err := getSealCipher(passphrase, sealedDat.Salt)
+	if err != nil {
+		return
+	}
+
+	if len(sealedDat.Nonce) != aead.NonceSize() {
+		err = &errortypes.ParseError{
+			errors.New("utils: Invalid sealed data nonce"),
+		}
+		return
+	}
+
+	data, err = aead.Open(nil, sealedDat.Nonce, sealedDat.Data, nil)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "utils: Failed to decrypt sealed data"),
+		}
+		return
+	}
+
+	return
+}